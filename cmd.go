@@ -1,6 +1,6 @@
 package main
 
-type context struct {
+type cliContext struct {
 	Debug bool
 }
 
@@ -9,11 +9,11 @@ type context struct {
 // The Path field specifies the file path for the configuration file.
 type ConfigCommand struct {
 	// Path file path for configuration file
-	Path string `arg optional name:"path" help:"Paths to list." type:"path"`
+	Path string `arg:"" optional:"" name:"path" help:"Paths to list." type:"path"`
 }
 
 // Run in the case of a configuration parameter
-func (l *ConfigCommand) Run(ctx *context) error {
+func (l *ConfigCommand) Run(ctx *cliContext) error {
 	// fmt.Println("config file is ", l.Path)
 	ConfigFile = l.Path
 	return nil
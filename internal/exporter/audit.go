@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/fjacquet/nbu_exporter/internal/utils"
+)
+
+const auditLogsPath = "/security/auditlogs"
+
+// fetchAuditEventPage retrieves one page of audit events and counts them per category.
+func (c *nbuClient) fetchAuditEventPage(ctx context.Context, metrics *nbuMetrics, startTime time.Time, offset int) (int, error) {
+	var events models.AuditLogs
+
+	url := buildURL(c.baseURL, auditLogsPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+		queryParamFilter: fmt.Sprintf("auditTime gt %s", utils.ConvertTimeToNBUDate(startTime)),
+	})
+
+	if err := c.fetchData(ctx, models.CollectorAudit, url, &events); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorAudit)
+	checkPagination(c, models.CollectorAudit, len(events.Data), events.Meta.Pagination)
+
+	for _, data := range events.Data {
+		metrics.auditEvents[data.Attributes.Category]++
+	}
+
+	if len(events.Data) == 0 || events.Meta.Pagination.Offset >= events.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return events.Meta.Pagination.Next, nil
+}
+
+// fetchAuditEvents counts the audit events recorded within the scrapping interval.
+func (c *nbuClient) fetchAuditEvents(ctx context.Context, metrics *nbuMetrics) error {
+	interval, err := c.cfg.GetScrapingDuration()
+	if err != nil {
+		return err
+	}
+	startTime := c.now().Add(-interval).UTC()
+
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchAuditEventPage(ctx, metrics, startTime, offset)
+	})
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/logging"
+)
+
+// circuitBreaker stops sending requests to NetBackup after consecutive failures.
+// Once threshold requests in a row have failed, requests fail fast with ErrCircuitOpen
+// for the cooldown period. A single probe request is then let through: its success
+// closes the breaker, its failure opens it for another cooldown period.
+type circuitBreaker struct {
+	// threshold is the number of consecutive failures opening the breaker; 0 disables it.
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// newCircuitBreaker creates a closed breaker. A threshold of 0 disables it.
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen when the request must not be sent.
+// After the cooldown, only the first caller is allowed, as the probe.
+func (b *circuitBreaker) allow() error {
+	if b.threshold == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed request sent within ctx.
+// A request aborted because ctx is done, such as by the scrape deadline, says nothing about
+// NetBackup: it is not counted, and only ends the probe if it was one.
+func (b *circuitBreaker) record(ctx context.Context, err error) {
+	if b.threshold == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil && ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return
+	}
+	if err == nil {
+		if b.failures >= b.threshold {
+			logging.LogInfo("NetBackup answered again, closing the circuit breaker")
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+		if b.failures == b.threshold {
+			logging.LogWarning(fmt.Sprintf("%d consecutive NetBackup requests failed, opening the circuit breaker for %s", b.failures, b.cooldown))
+		}
+	}
+}
+
+// isOpen reports whether requests are currently short-circuited or waiting for a probe.
+func (b *circuitBreaker) isOpen() bool {
+	if b.threshold == 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
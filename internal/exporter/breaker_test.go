@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker opening after 2 failures for a minute, and a function
+// moving its clock forward.
+func newTestBreaker() (*circuitBreaker, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	return breaker, func(d time.Duration) { now = now.Add(d) }
+}
+
+// failRequests records n failed requests allowed by the breaker.
+func failRequests(t *testing.T, breaker *circuitBreaker, n int) {
+	t.Helper()
+	for range n {
+		if err := breaker.allow(); err != nil {
+			t.Fatalf("allow() error = %v, want the request allowed", err)
+		}
+		breaker.record(context.Background(), errors.New("connection refused"))
+	}
+}
+
+func TestCircuitBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	breaker, _ := newTestBreaker()
+
+	failRequests(t, breaker, 1)
+	if breaker.isOpen() {
+		t.Fatal("breaker open after 1 failure, want closed")
+	}
+	failRequests(t, breaker, 1)
+	if !breaker.isOpen() {
+		t.Fatal("breaker closed after 2 failures, want open")
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbe(t *testing.T) {
+	breaker, advance := newTestBreaker()
+	failRequests(t, breaker, 2)
+
+	advance(time.Minute)
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() after cooldown error = %v, want the probe allowed", err)
+	}
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() during the probe error = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed probe opens the breaker for another cooldown.
+	breaker.record(context.Background(), errors.New("connection refused"))
+	if err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("allow() after a failed probe error = %v, want ErrCircuitOpen", err)
+	}
+	advance(time.Minute)
+	if err := breaker.allow(); err != nil {
+		t.Errorf("allow() after the second cooldown error = %v, want the probe allowed", err)
+	}
+}
+
+func TestCircuitBreakerClosesAfterSuccessfulProbe(t *testing.T) {
+	breaker, advance := newTestBreaker()
+	failRequests(t, breaker, 2)
+	advance(time.Minute)
+
+	if err := breaker.allow(); err != nil {
+		t.Fatalf("allow() after cooldown error = %v, want the probe allowed", err)
+	}
+	breaker.record(context.Background(), nil)
+	if breaker.isOpen() {
+		t.Fatal("breaker open after a successful probe, want closed")
+	}
+	// The failure count starts over.
+	failRequests(t, breaker, 1)
+	if breaker.isOpen() {
+		t.Error("breaker open after 1 new failure, want closed")
+	}
+}
+
+func TestCircuitBreakerIgnoresAbortedRequests(t *testing.T) {
+	breaker, _ := newTestBreaker()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, err := range []error{
+		fmt.Errorf("HTTP request failed: %w", context.Canceled),
+		fmt.Errorf("HTTP request failed: %w", context.DeadlineExceeded),
+	} {
+		for range 2 {
+			if err := breaker.allow(); err != nil {
+				t.Fatalf("allow() error = %v, want the request allowed", err)
+			}
+			breaker.record(ctx, err)
+		}
+	}
+	if breaker.isOpen() {
+		t.Error("breaker opened by requests aborted by their context, want closed")
+	}
+
+	// A timeout of the request itself, while the caller still waits, is a failure.
+	for range 2 {
+		if err := breaker.allow(); err != nil {
+			t.Fatalf("allow() error = %v, want the request allowed", err)
+		}
+		breaker.record(context.Background(), fmt.Errorf("HTTP request failed: %w", context.DeadlineExceeded))
+	}
+	if !breaker.isOpen() {
+		t.Error("breaker closed after 2 request timeouts, want open")
+	}
+}
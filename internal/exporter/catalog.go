@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+// catalogPolicyType is the policy type of the NetBackup catalog backups.
+const catalogPolicyType = "NBU_CATALOG"
+
+// fetchCatalogBackup records how long ago the last successful catalog backup ended.
+// The latest successful catalog job is queried whatever its age, so that catalog backups
+// missing for longer than the scrapping interval still show.
+func (c *nbuClient) fetchCatalogBackup(ctx context.Context, metrics *nbuMetrics) error {
+	var jobs models.Jobs
+
+	queryParams := map[string]string{
+		queryParamLimit:  "1",
+		queryParamSort:   "-endTime",
+		queryParamFilter: fmt.Sprintf("policyType eq '%s' and status eq 0", catalogPolicyType),
+	}
+	c.selectFields(queryParams, "job", jobFields)
+
+	url := buildURL(c.baseURL, c.jobsPath, queryParams)
+
+	if err := c.fetchData(ctx, models.CollectorCatalog, url, &jobs); err != nil {
+		return err
+	}
+	metrics.countPage(models.CollectorCatalog)
+
+	if len(jobs.Data) == 0 || jobs.Data[0].Attributes.EndTime.IsZero() {
+		return nil
+	}
+	metrics.catalogBackupAge = max(c.now().Sub(jobs.Data[0].Attributes.EndTime).Seconds(), 0)
+	metrics.catalogBackupFound = true
+	return nil
+}
@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+const hostsPath = "/config/hosts"
+
+// fetchClients counts the distinct hosts registered with the primary server.
+func (c *nbuClient) fetchClients(ctx context.Context, metrics *nbuMetrics) error {
+	var hosts models.Hosts
+
+	url := buildURL(c.baseURL, hostsPath, nil)
+
+	if err := c.fetchData(ctx, models.CollectorClients, url, &hosts); err != nil {
+		return err
+	}
+	metrics.countPage(models.CollectorClients)
+
+	names := make(map[string]struct{}, len(hosts.Hosts))
+	for _, host := range hosts.Hosts {
+		names[host.HostName] = struct{}{}
+	}
+	metrics.registeredClients = float64(len(names))
+	return nil
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+const diskPoolsPath = "/storage/disk-pools"
+
+// fetchDiskPoolPage retrieves one page of disk pools and records their usable, free and used capacity.
+func (c *nbuClient) fetchDiskPoolPage(ctx context.Context, metrics *nbuMetrics, offset int) (int, error) {
+	var pools models.DiskPools
+
+	url := buildURL(c.baseURL, diskPoolsPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+	})
+
+	if err := c.fetchData(ctx, models.CollectorDiskPools, url, &pools); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorDiskPools)
+	checkPagination(c, models.CollectorDiskPools, len(pools.Data), pools.Meta.Pagination)
+
+	for _, data := range pools.Data {
+		name := data.Attributes.Name
+		metrics.diskPools[fmt.Sprintf("%s|usable", name)] = float64(data.Attributes.UsableSizeBytes)
+		metrics.diskPools[fmt.Sprintf("%s|free", name)] = float64(data.Attributes.AvailableSpaceBytes)
+		metrics.diskPools[fmt.Sprintf("%s|used", name)] = float64(data.Attributes.UsedCapacityBytes)
+	}
+
+	if len(pools.Data) == 0 || pools.Meta.Pagination.Offset >= pools.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return pools.Meta.Pagination.Next, nil
+}
+
+// fetchDiskPools retrieves the capacity of every disk pool.
+// Pool capacity can differ from the capacity reported by the storage units using the pool.
+func (c *nbuClient) fetchDiskPools(ctx context.Context, metrics *nbuMetrics) error {
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchDiskPoolPage(ctx, metrics, offset)
+	})
+}
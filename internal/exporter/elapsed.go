@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxElapsed bounds parsed elapsed times so that no input can overflow a time.Duration.
+const maxElapsed = 100 * 365 * 24 * time.Hour
+
+// parseElapsed converts a NetBackup elapsed time into a duration.
+// Depending on the NetBackup version, it is reported as "HH:MM:SS", "D days HH:MM:SS"
+// (or "1 day HH:MM:SS"), or a plain number of seconds. Any other input returns an error.
+func parseElapsed(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid elapsed time %q", value)
+	fields := strings.Fields(value)
+
+	switch {
+	case len(fields) == 1 && !strings.Contains(fields[0], ":"):
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || math.IsNaN(seconds) || seconds < 0 || seconds > maxElapsed.Seconds() {
+			return 0, invalid
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+
+	case len(fields) == 1:
+		elapsed, ok := parseClock(fields[0])
+		if !ok {
+			return 0, invalid
+		}
+		return elapsed, nil
+
+	case len(fields) == 3 && (fields[1] == "days" || fields[1] == "day"):
+		days, err := strconv.ParseUint(fields[0], 10, 16)
+		if err != nil {
+			return 0, invalid
+		}
+		elapsed, ok := parseClock(fields[2])
+		if !ok {
+			return 0, invalid
+		}
+		elapsed += time.Duration(days) * 24 * time.Hour
+		if elapsed > maxElapsed {
+			return 0, invalid
+		}
+		return elapsed, nil
+	}
+	return 0, invalid
+}
+
+// parseClock converts "HH:MM:SS" into a duration. Hours may exceed 23; minutes and
+// seconds must be below 60.
+func parseClock(value string) (time.Duration, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil || hours > uint64(maxElapsed/time.Hour) {
+		return 0, false
+	}
+	minutes, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil || minutes >= 60 {
+		return 0, false
+	}
+	seconds, err := strconv.ParseUint(parts[2], 10, 8)
+	if err != nil || seconds >= 60 {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, true
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseElapsed(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "00:00:00", want: 0},
+		{value: "01:02:03", want: time.Hour + 2*time.Minute + 3*time.Second},
+		{value: "36:00:00", want: 36 * time.Hour},
+		{value: "2 days 01:00:00", want: 49 * time.Hour},
+		{value: "1 day 00:00:30", want: 24*time.Hour + 30*time.Second},
+		{value: "90", want: 90 * time.Second},
+		{value: "1.5", want: 1500 * time.Millisecond},
+		{value: "", wantErr: true},
+		{value: "01:60:00", wantErr: true},
+		{value: "01:02", wantErr: true},
+		{value: "-5", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "2 weeks 01:00:00", wantErr: true},
+		{value: "99999999999 days 00:00:00", wantErr: true},
+	} {
+		got, err := parseElapsed(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseElapsed(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseElapsed(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// FuzzParseElapsed checks that no input makes parseElapsed panic or return a duration
+// outside [0, maxElapsed].
+func FuzzParseElapsed(f *testing.F) {
+	for _, seed := range []string{"01:02:03", "2 days 01:00:00", "90", "1e300", "18446744073709551615:00:00"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		elapsed, err := parseElapsed(value)
+		if err == nil && (elapsed < 0 || elapsed > maxElapsed) {
+			t.Errorf("parseElapsed(%q) = %v, outside [0, %v]", value, elapsed, maxElapsed)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCacheDisabled is returned when a cache refresh is requested while caching is disabled.
+var ErrCacheDisabled = errors.New("metrics caching is disabled")
+
+// ErrCircuitOpen is returned without contacting NetBackup while the circuit breaker is
+// open after consecutive failures.
+var ErrCircuitOpen = errors.New("circuit breaker open after consecutive NetBackup failures")
+
+// ErrPaginationStalled is returned when the server returns a next page offset that does
+// not advance, which would otherwise make the pagination loop forever.
+var ErrPaginationStalled = errors.New("pagination offset did not advance")
+
+// ErrPartialResults is matched, through errors.Is, by the errors of paginated fetches that
+// failed after some pages were collected. The metrics of those pages are still reported.
+var ErrPartialResults = errors.New("partial results")
+
+// ErrUnsupportedAPIVersion is matched, through errors.Is, by errors caused by the server
+// rejecting the requested API version with HTTP 406 Not Acceptable.
+var ErrUnsupportedAPIVersion = errors.New("unsupported API version")
+
+// APIVersionError reports the API version rejected by the server for a URL.
+type APIVersionError struct {
+	Version string
+	URL     string
+}
+
+// Error implements the error interface.
+func (e *APIVersionError) Error() string {
+	return fmt.Sprintf("%s rejected API version %q with 406 Not Acceptable", e.URL, e.Version)
+}
+
+// Is lets errors.Is match the error against ErrUnsupportedAPIVersion.
+func (e *APIVersionError) Is(target error) bool {
+	return target == ErrUnsupportedAPIVersion
+}
@@ -0,0 +1,29 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFetchDataReturnsAPIVersionErrorOn406(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotAcceptable)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.APIVersion = "11.0"
+
+	var storages struct{}
+	err := newNbuClient(cfg).fetchData(context.Background(), "storage", server.URL, &storages)
+	if !errors.Is(err, ErrUnsupportedAPIVersion) {
+		t.Fatalf("fetchData() error = %v, want one matching ErrUnsupportedAPIVersion", err)
+	}
+	var versionErr *APIVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != "11.0" {
+		t.Errorf("fetchData() error = %#v, want an APIVersionError for version 11.0", err)
+	}
+	if errors.Is(errors.New("other failure"), ErrUnsupportedAPIVersion) {
+		t.Error("an unrelated error matches ErrUnsupportedAPIVersion")
+	}
+}
@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Health is the exporter status reported by HealthHandler.
+type Health struct {
+	// LastScrape is the time of the last collection from NetBackup, if any.
+	LastScrape *time.Time `json:"lastScrape,omitempty"`
+	// LastScrapeSuccess reports whether every fetch of the last collection succeeded.
+	LastScrapeSuccess bool `json:"lastScrapeSuccess"`
+	// LastSuccess is the time of the last fully successful collection, if any.
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// APIVersion is the configured NetBackup API version, empty for plain JSON.
+	APIVersion string `json:"apiVersion"`
+}
+
+// health returns the current status of the collector.
+func (collector *NbuCollector) health() Health {
+	collector.mu.RLock()
+	defer collector.mu.RUnlock()
+	health := Health{
+		LastScrapeSuccess: !collector.lastAttempt.IsZero() && collector.lastErr == nil,
+		APIVersion:        collector.cfg.NbuServer.APIVersion,
+	}
+	if !collector.lastAttempt.IsZero() {
+		lastAttempt := collector.lastAttempt
+		health.LastScrape = &lastAttempt
+	}
+	if !collector.lastSuccess.IsZero() {
+		lastSuccess := collector.lastSuccess
+		health.LastSuccess = &lastSuccess
+	}
+	return health
+}
+
+// HealthHandler returns a handler reporting the status of the last collection as JSON,
+// or as plain text when the Accept header asks for text/plain but not JSON.
+// It always answers 200 as long as the exporter runs, and is left unauthenticated for
+// liveness and readiness probes, so it does not report the NetBackup host.
+func (collector *NbuCollector) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := collector.health()
+		accept := r.Header.Get(headerAccept)
+		if strings.Contains(accept, "text/plain") && !strings.Contains(accept, "json") {
+			w.Header().Set(headerContentType, "text/plain; charset=utf-8")
+			fmt.Fprintln(w, "OK")
+			if health.LastScrape != nil {
+				fmt.Fprintf(w, "last scrape: %s (success: %t)\n", health.LastScrape.Format(time.RFC3339), health.LastScrapeSuccess)
+			}
+			return
+		}
+		w.Header().Set(headerContentType, contentType)
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// getHealth requests the health endpoint with the Accept header and returns the response.
+func getHealth(t *testing.T, collector *NbuCollector, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	collector.HealthHandler().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("/health status = %d, want %d", w.Code, http.StatusOK)
+	}
+	return w
+}
+
+func TestHealthHandlerReportsLastScrape(t *testing.T) {
+	var failing atomic.Bool
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":1}}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.APIVersion = "12.0"
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	collector := NewNbuCollector(cfg)
+	metrics := collector.Handler(promhttp.HandlerOpts{})
+
+	var health map[string]any
+	if err := json.Unmarshal(getHealth(t, collector, "").Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := health["lastScrape"]; ok || health["lastScrapeSuccess"] != false {
+		t.Errorf("/health before any scrape = %v, want no lastScrape and lastScrapeSuccess false", health)
+	}
+
+	metrics.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	w := getHealth(t, collector, "application/json")
+	if got := w.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("/health Content-Type = %q, want %q", got, contentType)
+	}
+	health = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"lastScrape", "lastSuccess"} {
+		if _, ok := health[key].(string); !ok {
+			t.Errorf("/health after a successful scrape has %s = %v, want a timestamp", key, health[key])
+		}
+	}
+	if health["lastScrapeSuccess"] != true || health["apiVersion"] != "12.0" {
+		t.Errorf("/health after a successful scrape = %v, want lastScrapeSuccess true and apiVersion 12.0", health)
+	}
+	if _, ok := health["hosts"]; ok {
+		t.Errorf("/health = %v, want the NetBackup host left out", health)
+	}
+	lastSuccess := health["lastSuccess"]
+
+	failing.Store(true)
+	metrics.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	health = nil
+	if err := json.Unmarshal(getHealth(t, collector, "").Body.Bytes(), &health); err != nil {
+		t.Fatal(err)
+	}
+	if health["lastScrapeSuccess"] != false || health["lastSuccess"] != lastSuccess {
+		t.Errorf("/health after a failed scrape = %v, want lastScrapeSuccess false and lastSuccess %v", health, lastSuccess)
+	}
+
+	body := getHealth(t, collector, "text/plain").Body.String()
+	if !strings.HasPrefix(body, "OK\n") || !strings.Contains(body, "(success: false)") {
+		t.Errorf("/health as text/plain = %q, want OK and the last scrape outcome", body)
+	}
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/fjacquet/nbu_exporter/internal/utils"
+)
+
+const imagesPath = "/catalog/images"
+
+// fetchImagePage retrieves one page of catalog images and aggregates their count and size per policy type.
+func (c *nbuClient) fetchImagePage(ctx context.Context, metrics *nbuMetrics, startTime time.Time, offset int) (int, error) {
+	var images models.Images
+
+	url := buildURL(c.baseURL, imagesPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+		queryParamFilter: fmt.Sprintf("backupTime gt %s", utils.ConvertTimeToNBUDate(startTime)),
+	})
+
+	if err := c.fetchData(ctx, models.CollectorImages, url, &images); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorImages)
+	checkPagination(c, models.CollectorImages, len(images.Data), images.Meta.Pagination)
+
+	for _, data := range images.Data {
+		metrics.imagesCount[data.Attributes.PolicyType]++
+		metrics.imagesBytes[data.Attributes.PolicyType] += float64(data.Attributes.Kilobytes * 1024)
+	}
+
+	if len(images.Data) == 0 || images.Meta.Pagination.Offset >= images.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return images.Meta.Pagination.Next, nil
+}
+
+// fetchImages aggregates the catalog images backed up within the scrapping interval.
+func (c *nbuClient) fetchImages(ctx context.Context, metrics *nbuMetrics) error {
+	interval, err := c.cfg.GetScrapingDuration()
+	if err != nil {
+		return err
+	}
+	startTime := c.now().Add(-interval).UTC()
+
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchImagePage(ctx, metrics, startTime, offset)
+	})
+}
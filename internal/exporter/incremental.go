@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// jobTotals accumulates the job metrics across collections with nbuserver.incrementalJobs.
+// Each collection only fetches the jobs ended after the latest end time seen so far, and
+// its values are added to the totals.
+type jobTotals struct {
+	// mu is held for a whole jobs collection, so that concurrent collections neither fetch
+	// nor count the same jobs twice.
+	mu sync.Mutex
+	// lastEnd is the latest end time of the jobs counted so far, zero before the first collection.
+	lastEnd time.Time
+	metrics *nbuMetrics
+}
+
+// newJobTotals returns empty totals.
+func newJobTotals() *jobTotals {
+	return &jobTotals{metrics: newNbuMetrics()}
+}
+
+// summedJobMaps returns the job maps of m whose values are sums over the jobs, which can
+// therefore be added across collections.
+func summedJobMaps(m *nbuMetrics) []map[string]float64 {
+	return []map[string]float64{
+		m.jobsCount, m.jobsSize, m.jobsStatusCount, m.jobsScheduleCount, m.jobsSubtypeCount,
+		m.jobsFailedBytes, m.jobsRetries, m.jobsTransport, m.policyJobs, m.policySuccesses,
+		m.backupBytes, m.backupSeconds,
+	}
+}
+
+// seenJobSets returns the sets of m filled from the jobs, such as the backed up clients.
+func seenJobSets(m *nbuMetrics) []map[string]struct{} {
+	return []map[string]struct{}{m.backedUpClients, m.policies}
+}
+
+// add adds the jobs of a collection to the totals, then replaces the summed maps and the
+// sets of the collection with the totals. The values of a failed collection are dropped instead, and
+// its jobs fetched again by the next one, so that no job is counted twice.
+// It must be called with mu held.
+func (t *jobTotals) add(metrics *nbuMetrics, ok bool) {
+	totals := summedJobMaps(t.metrics)
+	for i, values := range summedJobMaps(metrics) {
+		if ok {
+			for key, value := range values {
+				totals[i][key] += value
+			}
+		}
+		clear(values)
+		maps.Copy(values, totals[i])
+	}
+
+	totalSets := seenJobSets(t.metrics)
+	for i, set := range seenJobSets(metrics) {
+		if ok {
+			maps.Copy(totalSets[i], set)
+		}
+		clear(set)
+		maps.Copy(set, totalSets[i])
+	}
+
+	if ok {
+		for policyType, end := range metrics.lastSuccessEnd {
+			if end.After(t.metrics.lastSuccessEnd[policyType]) {
+				t.metrics.lastSuccessEnd[policyType] = end
+			}
+		}
+		if metrics.lastJobEnd.After(t.lastEnd) {
+			t.lastEnd = metrics.lastJobEnd
+		}
+	}
+	clear(metrics.lastSuccessEnd)
+	maps.Copy(metrics.lastSuccessEnd, t.metrics.lastSuccessEnd)
+}
@@ -0,0 +1,162 @@
+package exporter
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// jobLog is the fake NetBackup job history, to which jobs can be added between collections.
+type jobLog struct {
+	mu   sync.Mutex
+	jobs []models.Job
+}
+
+// add records a backup job of the policy type ended at end.
+func (l *jobLog) add(policyType string, end time.Time) {
+	var job models.Job
+	job.Attributes.JobType = "BACKUP"
+	job.Attributes.PolicyType = policyType
+	job.Attributes.EndTime = end
+	l.mu.Lock()
+	l.jobs = append(l.jobs, job)
+	l.mu.Unlock()
+}
+
+// serve answers the lookback query with the page of one job at the requested offset, among
+// the jobs ended after the time of the filter.
+func (l *jobLog) serve(w http.ResponseWriter, r *http.Request) {
+	if !isLookbackQuery(r) {
+		writeJSON(w, `{"data":[]}`)
+		return
+	}
+	since, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(r.URL.Query().Get(queryParamFilter), "endTime gt "))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, _ := strconv.Atoi(r.URL.Query().Get(queryParamOffset))
+
+	var matching []models.Job
+	l.mu.Lock()
+	for _, job := range l.jobs {
+		if job.Attributes.EndTime.After(since) {
+			matching = append(matching, job)
+		}
+	}
+	l.mu.Unlock()
+
+	var jobs models.Jobs
+	if offset < len(matching) {
+		jobs.Data = matching[offset : offset+1]
+	}
+	jobs.Meta.Pagination.Offset = offset
+	jobs.Meta.Pagination.Next = offset + 1
+	jobs.Meta.Pagination.Last = len(matching) - 1
+	body, err := json.Marshal(jobs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, string(body))
+}
+
+// incrementalCollector returns a registry collecting the jobs of the log incrementally.
+func incrementalCollector(t *testing.T, log *jobLog, maxJobSeries int) *prometheus.Registry {
+	t.Helper()
+	server := newTestServer(t, log.serve)
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorJobs}
+	cfg.NbuServer.IncrementalJobs = true
+	cfg.NbuServer.MaxJobSeries = maxJobSeries
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+	return registry
+}
+
+// jobsCountTotals collects the registry and returns the nbu_jobs_count_total counters by
+// policy type, failing unless they are counters.
+func jobsCountTotals(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	totals := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() == "nbu_jobs_count" {
+			t.Fatal("nbu_jobs_count collected with incrementalJobs, want nbu_jobs_count_total")
+		}
+		if family.GetName() != "nbu_jobs_count_total" {
+			continue
+		}
+		if family.GetType() != dto.MetricType_COUNTER {
+			t.Fatalf("nbu_jobs_count_total type = %v, want COUNTER", family.GetType())
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "policy_type" {
+					totals[label.GetValue()] = metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return totals
+}
+
+func TestIncrementalJobsCountsEachJobOnce(t *testing.T) {
+	end := time.Now().Add(-10 * time.Minute).UTC()
+	log := &jobLog{}
+	log.add("Standard", end)
+	log.add("Standard", end.Add(time.Minute))
+	registry := incrementalCollector(t, log, 0)
+
+	if got := jobsCountTotals(t, registry)["Standard"]; got != 2 {
+		t.Fatalf("nbu_jobs_count_total after the first collection = %v, want 2", got)
+	}
+
+	// One more job ends; the jobs of the first collection are still within the lookback window.
+	log.add("Standard", end.Add(2*time.Minute))
+	if got := jobsCountTotals(t, registry)["Standard"]; got != 3 {
+		t.Errorf("nbu_jobs_count_total after the second collection = %v, want 3", got)
+	}
+}
+
+func TestIncrementalJobsTotalsNeverDecreaseWhenTopSeriesChange(t *testing.T) {
+	end := time.Now().Add(-10 * time.Minute).UTC()
+	log := &jobLog{}
+	log.add("Standard", end)
+	log.add("Standard", end.Add(time.Second))
+	log.add("Oracle", end.Add(2*time.Second))
+	log.add("Windows", end.Add(3*time.Second))
+	// A single series is kept besides the other one.
+	registry := incrementalCollector(t, log, 2)
+
+	first := jobsCountTotals(t, registry)
+	if want := map[string]float64{"Standard": 2, "other": 2}; !maps.Equal(first, want) {
+		t.Fatalf("nbu_jobs_count_total after the first collection = %v, want %v", first, want)
+	}
+
+	// Oracle now has the most jobs, in total and within the collection.
+	for i := range 3 {
+		log.add("Oracle", end.Add(time.Minute+time.Duration(i)*time.Second))
+	}
+	second := jobsCountTotals(t, registry)
+	for policyType, value := range first {
+		if second[policyType] < value {
+			t.Errorf("nbu_jobs_count_total{policy_type=%q} went from %v to %v, want no decrease", policyType, value, second[policyType])
+		}
+	}
+	if second["Oracle"] != 3 {
+		t.Errorf("nbu_jobs_count_total{policy_type=\"Oracle\"} = %v, want the 3 jobs of the second collection", second["Oracle"])
+	}
+}
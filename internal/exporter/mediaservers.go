@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+const mediaServersPath = "/config/media-servers"
+
+// fetchMediaServerPage retrieves one page of media servers and records whether each one is up.
+// A media server is considered up when its state is ACTIVE.
+func (c *nbuClient) fetchMediaServerPage(ctx context.Context, metrics *nbuMetrics, offset int) (int, error) {
+	var servers models.MediaServers
+
+	url := buildURL(c.baseURL, mediaServersPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+	})
+
+	if err := c.fetchData(ctx, models.CollectorMediaServers, url, &servers); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorMediaServers)
+	checkPagination(c, models.CollectorMediaServers, len(servers.Data), servers.Meta.Pagination)
+
+	for _, data := range servers.Data {
+		up := 0.0
+		if strings.EqualFold(data.Attributes.State, "active") {
+			up = 1
+		}
+		metrics.mediaServers[data.Attributes.Name] = up
+	}
+
+	if len(servers.Data) == 0 || servers.Meta.Pagination.Offset >= servers.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return servers.Meta.Pagination.Next, nil
+}
+
+// fetchMediaServers retrieves the state of every media server known to the primary server.
+func (c *nbuClient) fetchMediaServers(ctx context.Context, metrics *nbuMetrics) error {
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchMediaServerPage(ctx, metrics, offset)
+	})
+}
@@ -1,41 +1,226 @@
 package exporter
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fjacquet/nbu_exporter/internal/logging"
 	"github.com/fjacquet/nbu_exporter/internal/models"
 	"github.com/fjacquet/nbu_exporter/internal/utils"
 	"github.com/go-resty/resty/v2"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
-	pageLimit           = "100"
-	timeout             = 1 * time.Minute
-	contentType         = "application/json"
-	queryParamLimit     = "page[limit]"
-	queryParamOffset    = "page[offset]"
-	queryParamSort      = "sort"
-	queryParamFilter    = "filter"
-	headerAccept        = "Accept"
-	headerAuthorization = "Authorization"
+	pageLimit              = "100"
+	timeout                = 1 * time.Minute
+	contentType            = "application/json"
+	versionedMediaType     = "application/vnd.netbackup+json;version=%s"
+	queryParamLimit        = "page[limit]"
+	queryParamOffset       = "page[offset]"
+	queryParamSort         = "sort"
+	queryParamFilter       = "filter"
+	queryParamFields       = "fields[%s]"
+	headerAccept           = "Accept"
+	headerAuthorization    = "Authorization"
+	headerContentType      = "Content-Type"
+	headerRetryAfter       = "Retry-After"
+	headerContentEncoding  = "Content-Encoding"
+	headerDate             = "Date"
+	maxRateLimitRetries    = 3
+	defaultRetryAfter      = 1 * time.Second
+	otherJobSeries         = "other|other|other"
+	defaultJobsPath        = "/admin/jobs"
+	defaultStoragePath     = "/storage/storage-units"
+	defaultRequestIDHeader = "X-Request-ID"
+	defaultJobSort         = "jobId"
 )
 
+// Attributes requested with nbuserver.useFieldSelection, per resource type: only those
+// read by the exporter, to shrink the responses.
+var (
+	jobFields = []string{
+		"jobId", "jobType", "jobSubType", "policyType", "policyName", "scheduleType", "clientName",
+		"status", "state", "kilobytesTransferred", "transportType", "startTime", "endTime",
+		"activeTryStartTime", "jobQueueReason", "elapsedTime", "try",
+	}
+	storageUnitFields = []string{"name", "storageType", "storageServerType", "freeCapacityBytes", "usedCapacityBytes", "useWorm"}
+)
+
+// defaultAcceptedContentTypes are the response media types that carry JSON from the NetBackup API,
+// used unless nbuserver.acceptedContentTypes is set.
+var defaultAcceptedContentTypes = []string{"application/json", "application/vnd.netbackup+json"}
+
+// nbuClient queries the NetBackup API and keeps track of the authorization token in use.
+type nbuClient struct {
+	cfg         models.Config
+	client      *resty.Client
+	baseURL     string
+	jobsPath    string
+	storagePath string
+	// requestIDHeader is the header carrying the ID generated for each fetch.
+	requestIDHeader string
+	// acceptedContentTypes are the response media types decoded as JSON.
+	acceptedContentTypes []string
+	mu                   sync.RWMutex
+	token                string
+	// now returns the current time; it is used to compute the lookback filters.
+	now func() time.Time
+	// lastResponse is the Unix time in nanoseconds of the last HTTP response received.
+	lastResponse    atomic.Int64
+	requestDuration *prometheus.HistogramVec
+	rateLimited     prometheus.Counter
+	// paginationAnomalies counts the paginations stopped because the offset did not advance.
+	paginationAnomalies prometheus.Counter
+	// storageTypes are the storage unit types seen so far, still reported by
+	// nbu_storage_units_count with a count of 0 once the server has no unit of that type.
+	storageTypes   map[string]struct{}
+	storageTypesMu sync.Mutex
+	// paginationMetadataMissing counts the non-empty pages returned without pagination metadata.
+	paginationMetadataMissing *prometheus.CounterVec
+	// storageDuplicates counts the storage units reported under an already seen name and type.
+	storageDuplicates prometheus.Counter
+	// breaker short-circuits requests while NetBackup keeps failing.
+	breaker *circuitBreaker
+	// jobTotals accumulates the job metrics with nbuserver.incrementalJobs.
+	jobTotals *jobTotals
+	// clockSkew is the NetBackup clock minus the local clock in nanoseconds, measured from the
+	// Date header of the last response; clockSkewKnown is set once a Date header was received.
+	clockSkew      atomic.Int64
+	clockSkewKnown atomic.Bool
+}
+
+// newNbuClient creates a client for the NetBackup server described by the configuration.
+// The configured API key is used as the initial authorization token.
+func newNbuClient(cfg models.Config) *nbuClient {
+	return &nbuClient{
+		cfg:                  cfg,
+		client:               createHTTPClient(cfg),
+		baseURL:              fmt.Sprintf("%s://%s:%s%s", cfg.NbuServer.Scheme, cfg.NbuServer.Host, cfg.NbuServer.Port, cfg.NbuServer.URI),
+		jobsPath:             valueOrDefault(cfg.NbuServer.JobsPath, defaultJobsPath),
+		storagePath:          valueOrDefault(cfg.NbuServer.StoragePath, defaultStoragePath),
+		requestIDHeader:      valueOrDefault(cfg.NbuServer.RequestIDHeader, defaultRequestIDHeader),
+		acceptedContentTypes: listOrDefault(cfg.NbuServer.AcceptedContentTypes, defaultAcceptedContentTypes),
+		token:                cfg.NbuServer.APIKey,
+		now:                  time.Now,
+		storageTypes:         make(map[string]struct{}),
+		jobTotals:            newJobTotals(),
+		breaker:              newCircuitBreaker(cfg.NbuServer.CircuitBreaker.Failures, cfg.GetCircuitBreakerCooldown()),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        models.MetricAPIRequestDurationSeconds,
+			Help:        "The duration of NetBackup API requests in seconds",
+			Buckets:     prometheus.DefBuckets,
+			ConstLabels: cfg.Server.ConstLabels,
+		}, []string{"endpoint"}),
+		rateLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        models.MetricAPIRateLimitedTotal,
+			Help:        "The quantity of NetBackup API responses with status 429 Too Many Requests",
+			ConstLabels: cfg.Server.ConstLabels,
+		}),
+		paginationAnomalies: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        models.MetricPaginationAnomaliesTotal,
+			Help:        "The quantity of paginations stopped because the server returned a non-advancing offset",
+			ConstLabels: cfg.Server.ConstLabels,
+		}),
+		paginationMetadataMissing: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        models.MetricPaginationMetadataMissingTotal,
+			Help:        "The quantity of non-empty NetBackup responses without pagination metadata, after which the pagination stops",
+			ConstLabels: cfg.Server.ConstLabels,
+		}, []string{"endpoint"}),
+		storageDuplicates: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        models.MetricStorageDuplicateKeysTotal,
+			Help:        "The quantity of storage units reported under the name and type of another one, told apart by their ID",
+			ConstLabels: cfg.Server.ConstLabels,
+		}),
+	}
+}
+
+// metrics returns the metrics maintained by the client, by name.
+func (c *nbuClient) metrics() map[string]prometheus.Collector {
+	return map[string]prometheus.Collector{
+		models.MetricAPIRequestDurationSeconds:      c.requestDuration,
+		models.MetricAPIRateLimitedTotal:            c.rateLimited,
+		models.MetricPaginationAnomaliesTotal:       c.paginationAnomalies,
+		models.MetricPaginationMetadataMissingTotal: c.paginationMetadataMissing,
+		models.MetricStorageDuplicateKeysTotal:      c.storageDuplicates,
+	}
+}
+
+// valueOrDefault returns the value, or the fallback when the value is empty.
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// listOrDefault returns the values, or the fallback when there are none.
+func listOrDefault(values, fallback []string) []string {
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
+
 // createHTTPClient initializes and returns a Resty client configured for HTTP requests.
-func createHTTPClient() *resty.Client {
-	return resty.New().
-		SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true}).
-		SetTimeout(timeout)
+// Requests go through nbuserver.proxyURL when it is set. The client timeout is the longest of
+// the default and per-endpoint timeouts; shorter ones are applied per request.
+func createHTTPClient(cfg models.Config) *resty.Client {
+	clientTimeout := max(
+		timeout,
+		cfg.EndpointTimeout(models.CollectorJobs, timeout),
+		cfg.EndpointTimeout(models.CollectorStorage, timeout),
+	)
+	client := resty.New().
+		SetTLSClientConfig(&tls.Config{
+			InsecureSkipVerify: cfg.SkipTLSVerify(),
+			MinVersion:         cfg.GetMinTLSVersion(),
+		}).
+		SetTimeout(clientTimeout)
+	if cfg.NbuServer.ProxyURL != "" {
+		client.SetProxy(cfg.NbuServer.ProxyURL)
+	}
+	return client
+}
+
+// getHeaders returns the headers sent with every NetBackup API request.
+// When an API version is configured, the versioned NetBackup media type is requested
+// unless nbuserver.acceptFormat is plain.
+func getHeaders(cfg models.Config, token string) map[string]string {
+	accept := contentType
+	if cfg.NbuServer.APIVersion != "" && cfg.NbuServer.AcceptFormat != models.AcceptFormatPlain {
+		accept = fmt.Sprintf(versionedMediaType, cfg.NbuServer.APIVersion)
+	}
+	headers := make(map[string]string, len(cfg.NbuServer.ExtraHeaders)+2)
+	for name, value := range cfg.NbuServer.ExtraHeaders {
+		headers[name] = value
+	}
+	headers[headerAccept] = accept
+	headers[headerAuthorization] = token
+	return headers
 }
 
 // buildURL constructs a complete URL from base, path, and query parameters.
+// The path is appended to the path of the base URL, which holds the API prefix.
 func buildURL(baseURL, path string, queryParams map[string]string) string {
 	u, _ := url.Parse(baseURL)
-	u.Path = path
+	u = u.JoinPath(path)
 	q := u.Query()
 	for key, value := range queryParams {
 		q.Set(key, value)
@@ -44,118 +229,636 @@ func buildURL(baseURL, path string, queryParams map[string]string) string {
 	return u.String()
 }
 
-// fetchData sends an HTTP GET request and unmarshals the response body into the target object.
-func fetchData(client *resty.Client, url string, headers map[string]string, target interface{}) error {
-	resp, err := client.R().
-		SetHeaders(headers).
+// isJSONContentType reports whether the Content-Type header contains one of the accepted media types.
+func (c *nbuClient) isJSONContentType(value string) bool {
+	for _, accepted := range c.acceptedContentTypes {
+		if strings.Contains(value, accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentToken returns the authorization token used for API requests.
+func (c *nbuClient) currentToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// get sends an HTTP GET request with the current authorization token and returns the response
+// with its body, read up to nbuserver.maxResponseBytes when that limit is set.
+// The transport requests and decompresses gzip transparently; a body still marked as gzip
+// encoded, e.g. sent by a proxy or when Accept-Encoding was set explicitly, is decompressed here,
+// before the limit applies.
+// The request, body included, must complete within the endpoint timeout and before ctx is done.
+// Its duration is observed in the request histogram under the endpoint label.
+func (c *nbuClient) get(ctx context.Context, endpoint, url, requestID string) (*resty.Response, []byte, error) {
+	start := time.Now()
+	defer func() {
+		c.requestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.EndpointTimeout(endpoint, timeout))
+	defer cancel()
+
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		SetHeaders(getHeaders(c.cfg, c.currentToken())).
+		SetHeader(c.requestIDHeader, requestID).
 		Get(url)
+	if err != nil {
+		return resp, nil, err
+	}
+	received := time.Now()
+	c.lastResponse.Store(received.UnixNano())
+	if date, err := http.ParseTime(resp.Header().Get(headerDate)); err == nil {
+		// The server time is compared with the middle of the request.
+		c.clockSkew.Store(int64(date.Sub(start.Add(received.Sub(start) / 2))))
+		c.clockSkewKnown.Store(true)
+	}
+
+	defer resp.RawBody().Close()
+	var reader io.Reader = resp.RawBody()
+	if strings.EqualFold(resp.Header().Get(headerContentEncoding), "gzip") {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return resp, nil, fmt.Errorf("invalid gzip response body: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	body, err := readBody(reader, c.cfg.NbuServer.MaxResponseBytes)
+	return resp, body, err
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+// An absent header yields defaultRetryAfter; an unparsable one is reported as not ok.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return defaultRetryAfter, true
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// readBody reads the whole body, failing when it is larger than limit bytes.
+// A limit of zero or less means no limit.
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("response body exceeds maxResponseBytes (%d bytes)", limit)
+	}
+	return data, nil
+}
+
+// respondedSince reports whether the server answered any request, whatever its status, since the given time.
+func (c *nbuClient) respondedSince(t time.Time) bool {
+	return c.lastResponse.Load() >= t.UnixNano()
+}
+
+// serverClockSkew returns the NetBackup clock minus the local clock, measured from the Date
+// header of the last response. It reports false when no Date header was received yet.
+func (c *nbuClient) serverClockSkew() (time.Duration, bool) {
+	return time.Duration(c.clockSkew.Load()), c.clockSkewKnown.Load()
+}
+
+// authenticate requests a fresh token from the configured token endpoint.
+func (c *nbuClient) authenticate(ctx context.Context) error {
+	var token models.Token
+	url := buildURL(c.baseURL, c.cfg.NbuServer.TokenEndpoint, nil)
+	headers := getHeaders(c.cfg, "")
+	delete(headers, headerAuthorization)
+
+	resp, err := c.client.R().
+		SetContext(ctx).
+		SetHeaders(headers).
+		SetBody(models.LoginRequest{
+			UserName:   c.cfg.NbuServer.Username,
+			Password:   c.cfg.NbuServer.Password,
+			DomainName: c.cfg.NbuServer.Domain,
+			DomainType: c.cfg.NbuServer.DomainType,
+		}).
+		Post(url)
+	if err != nil {
+		return fmt.Errorf("authentication request to %s failed: %w", url, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("authentication request to %s returned %s", url, resp.Status())
+	}
+	if err := json.Unmarshal(resp.Body(), &token); err != nil {
+		return fmt.Errorf("failed to unmarshal token from %s: %w", url, err)
+	}
+	if token.Token == "" {
+		return fmt.Errorf("authentication response from %s contains no token", url)
+	}
+
+	c.mu.Lock()
+	c.token = token.Token
+	c.mu.Unlock()
+	logging.LogInfo("Obtained a new NetBackup API token")
+	return nil
+}
+
+// newRequestID returns a random identifier for correlating a request with the NetBackup logs.
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// fetchData sends an HTTP GET request and unmarshals the response body into the target object.
+// The request, and its retries, carry a new request ID that is also included in the returned error.
+// While the circuit breaker is open, it fails with ErrCircuitOpen without sending the request.
+func (c *nbuClient) fetchData(ctx context.Context, endpoint, url string, target interface{}) error {
+	if err := c.breaker.allow(); err != nil {
+		return fmt.Errorf("%s: %w", url, err)
+	}
+	requestID := newRequestID()
+	err := c.fetchDataWithID(ctx, endpoint, url, requestID, target)
+	c.breaker.record(ctx, err)
+	if err != nil {
+		return fmt.Errorf("request %s: %w", requestID, err)
+	}
+	return nil
+}
+
+// fetchDataWithID sends the request of fetchData with the given request ID.
+// When a token endpoint is configured, a 401 response triggers one re-authentication and retry.
+// A 429 response is retried after the delay given by its Retry-After header, up to
+// maxRateLimitRetries times and as long as the delay fits in the endpoint timeout.
+// A 406 response is reported as an APIVersionError matching ErrUnsupportedAPIVersion.
+func (c *nbuClient) fetchDataWithID(ctx context.Context, endpoint, url, requestID string, target interface{}) error {
+	resp, body, err := c.get(ctx, endpoint, url, requestID)
+	for attempt := 0; err == nil && resp.StatusCode() == http.StatusTooManyRequests; attempt++ {
+		c.rateLimited.Inc()
+		wait, ok := retryAfter(resp.Header().Get(headerRetryAfter), time.Now())
+		if !ok || attempt >= maxRateLimitRetries || wait > c.cfg.EndpointTimeout(endpoint, timeout) {
+			return fmt.Errorf("%s rate limited the request (429 Too Many Requests)", url)
+		}
+		logging.LogWarning(fmt.Sprintf("%s rate limited request %s, retrying in %s", url, requestID, wait))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s rate limited the request: %w", url, ctx.Err())
+		case <-time.After(wait):
+		}
+		resp, body, err = c.get(ctx, endpoint, url, requestID)
+	}
+	if err == nil && resp.StatusCode() == http.StatusUnauthorized && c.cfg.NbuServer.TokenEndpoint != "" {
+		if err := c.authenticate(ctx); err != nil {
+			return fmt.Errorf("re-authentication after 401 from %s failed: %w", url, err)
+		}
+		resp, body, err = c.get(ctx, endpoint, url, requestID)
+	}
 	if err != nil {
 		return fmt.Errorf("HTTP request to %s failed: %w", url, err)
 	}
-	if err := json.Unmarshal(resp.Body(), target); err != nil {
+	if resp.StatusCode() == http.StatusNotAcceptable {
+		return &APIVersionError{Version: c.cfg.NbuServer.APIVersion, URL: url}
+	}
+	if ct := resp.Header().Get(headerContentType); !c.isJSONContentType(ct) {
+		if strings.Contains(ct, "text/html") {
+			return fmt.Errorf("%s returned an HTML page (status %s) instead of JSON: check the NetBackup scheme, host, port and uri", url, resp.Status())
+		}
+		return fmt.Errorf("%s returned unexpected Content-Type %q (status %s)", url, ct, resp.Status())
+	}
+	if err := json.Unmarshal(body, target); err != nil {
 		return fmt.Errorf("failed to unmarshal response from %s: %w", url, err)
 	}
 	return nil
 }
 
 // fetchStorage retrieves and processes storage unit information.
-func fetchStorage(disks map[string]float64, cfg models.Config) error {
+// Every unit is counted per storage type, while tape units are excluded from capacity metrics.
+// A storage type seen in an earlier fetch is reported with a count of 0 once it has no unit,
+// including when the response has a null data array.
+// A disk storage unit with the name and type of a previous one is reported as "name#id".
+func (c *nbuClient) fetchStorage(ctx context.Context, metrics *nbuMetrics) error {
 	var storages models.Storages
-	nbuRoot := fmt.Sprintf("%s://%s:%s%s", cfg.NbuServer.Scheme, cfg.NbuServer.Host, cfg.NbuServer.Port, cfg.NbuServer.URI)
 
-	url := buildURL(nbuRoot, "/storage/storage-units", map[string]string{
+	queryParams := map[string]string{
 		queryParamLimit:  pageLimit,
 		queryParamOffset: "0",
-	})
-
-	headers := map[string]string{
-		headerAccept:        contentType,
-		headerAuthorization: cfg.NbuServer.APIKey,
 	}
+	c.selectFields(queryParams, "storageUnit", storageUnitFields)
+
+	url := buildURL(c.baseURL, c.storagePath, queryParams)
 
-	err := fetchData(createHTTPClient(), url, headers, &storages)
+	err := c.fetchData(ctx, models.CollectorStorage, url, &storages)
 	if err != nil {
 		logging.LogError(fmt.Sprintf("Error fetching storage data: %v", err))
 		return err
 	}
+	metrics.countPage(models.CollectorStorage)
 
+	c.storageTypesMu.Lock()
 	for _, data := range storages.Data {
+		c.storageTypes[data.Attributes.StorageType] = struct{}{}
+	}
+	for storageType := range c.storageTypes {
+		metrics.storageUnits[storageType] = 0
+	}
+	c.storageTypesMu.Unlock()
+	for _, data := range storages.Data {
+		metrics.storageUnits[data.Attributes.StorageType]++
 		if data.Attributes.StorageType == "Tape" {
 			continue
 		}
 
 		stuName := data.Attributes.Name
 		stuType := data.Attributes.StorageServerType
-		disks[fmt.Sprintf("%s|%s|free", stuName, stuType)] = float64(data.Attributes.FreeCapacityBytes)
-		disks[fmt.Sprintf("%s|%s|used", stuName, stuType)] = float64(data.Attributes.UsedCapacityBytes)
+		if _, ok := metrics.disks[fmt.Sprintf("%s|%s|free", stuName, stuType)]; ok {
+			// Keep both units rather than letting the second one overwrite the first.
+			c.storageDuplicates.Inc()
+			logging.LogWarning(fmt.Sprintf("Duplicate storage unit %s of type %s, reporting it as %s#%s", stuName, stuType, stuName, data.ID))
+			stuName = fmt.Sprintf("%s#%s", stuName, data.ID)
+		}
+		metrics.disks[fmt.Sprintf("%s|%s|free", stuName, stuType)] = float64(data.Attributes.FreeCapacityBytes)
+		metrics.disks[fmt.Sprintf("%s|%s|used", stuName, stuType)] = float64(data.Attributes.UsedCapacityBytes)
+		metrics.storageWorm[stuName] = 0
+		if data.Attributes.UseWorm {
+			metrics.storageWorm[stuName] = 1
+		}
 	}
 	return nil
 }
 
 // fetchJobDetails retrieves and processes job details for a specific offset.
-func fetchJobDetails(client *resty.Client, jobsSize, jobsCount, jobsStatusCount map[string]float64, offset int, cfg models.Config) (int, error) {
-	var jobs models.Jobs
-	nbuRoot := fmt.Sprintf("%s://%s:%s%s", cfg.NbuServer.Scheme, cfg.NbuServer.Host, cfg.NbuServer.Port, cfg.NbuServer.URI)
-
-	duration, err := time.ParseDuration("-" + cfg.Server.ScrappingInterval)
+// Jobs ended after startTime are selected, unless a custom job filter is configured.
+func (c *nbuClient) fetchJobDetails(ctx context.Context, metrics *nbuMetrics, startTime time.Time, offset int) (int, error) {
+	jobs, err := c.fetchJobPage(ctx, metrics, startTime, offset)
 	if err != nil {
-		return -1, fmt.Errorf("invalid scrapping interval: %w", err)
+		return -1, err
+	}
+	for _, job := range jobs.Data {
+		c.countJob(metrics, job)
 	}
+	return nextJobOffset(jobs), nil
+}
+
+// fetchJobPage retrieves the page of jobs at offset.
+func (c *nbuClient) fetchJobPage(ctx context.Context, metrics *nbuMetrics, startTime time.Time, offset int) (models.Jobs, error) {
+	var jobs models.Jobs
 
-	startTime := time.Now().Add(duration).UTC()
 	queryParams := map[string]string{
 		queryParamLimit:  "1",
 		queryParamOffset: fmt.Sprintf("%d", offset),
-		queryParamSort:   "jobId",
-		queryParamFilter: fmt.Sprintf("endTime%%20gt%%20%s", utils.ConvertTimeToNBUDate(startTime)),
+		queryParamSort:   valueOrDefault(c.cfg.NbuServer.JobSort, defaultJobSort),
+		queryParamFilter: fmt.Sprintf("endTime gt %s", utils.ConvertTimeToNBUDate(startTime)),
 	}
+	if c.cfg.NbuServer.JobFilter != "" {
+		queryParams[queryParamFilter] = c.cfg.NbuServer.JobFilter
+	}
+	c.selectFields(queryParams, "job", jobFields)
+
+	url := buildURL(c.baseURL, c.jobsPath, queryParams)
 
-	url := buildURL(nbuRoot, "/admin/jobs", queryParams)
-	headers := map[string]string{
-		headerAccept:        contentType,
-		headerAuthorization: cfg.NbuServer.APIKey,
+	if err := c.fetchData(ctx, models.CollectorJobs, url, &jobs); err != nil {
+		return jobs, err
 	}
+	metrics.countPage(models.CollectorJobs)
+	checkPagination(c, models.CollectorJobs, len(jobs.Data), jobs.Meta.Pagination)
+	return jobs, nil
+}
 
-	if err := fetchData(client, url, headers, &jobs); err != nil {
-		return -1, err
+// nextJobOffset returns the offset of the page following jobs, or -1 after the last page.
+func nextJobOffset(jobs models.Jobs) int {
+	if len(jobs.Data) == 0 || jobs.Meta.Pagination.Offset == jobs.Meta.Pagination.Last {
+		return -1
 	}
+	return jobs.Meta.Pagination.Next
+}
 
-	if len(jobs.Data) == 0 {
-		return -1, nil
+// countJob adds a job to the job metrics.
+func (c *nbuClient) countJob(metrics *nbuMetrics, job models.Job) {
+	if job.Attributes.EndTime.After(metrics.lastJobEnd) {
+		metrics.lastJobEnd = job.Attributes.EndTime
+	}
+	if !c.policyAllowed(job.Attributes.PolicyName) {
+		return
+	}
+	if job.Attributes.State == "ACTIVE" && !c.cfg.NbuServer.IncludeActiveJobs {
+		return
+	}
+	if slices.Contains(c.cfg.NbuServer.ExcludeStatuses, job.Attributes.Status) {
+		return
 	}
 
-	job := jobs.Data[0]
 	key := fmt.Sprintf("%s|%s|%d", job.Attributes.JobType, job.Attributes.PolicyType, job.Attributes.Status)
 	key2 := fmt.Sprintf("%s|%d", job.Attributes.JobType, job.Attributes.Status)
 
-	jobsCount[key]++
-	jobsStatusCount[key2]++
-	jobsSize[key] += float64(job.Attributes.KilobytesTransferred * 1024)
+	metrics.jobsCount[key]++
+	metrics.jobsStatusCount[key2]++
+	metrics.jobsScheduleCount[fmt.Sprintf("%s|%d", job.Attributes.ScheduleType, job.Attributes.Status)]++
+	if job.Attributes.TransportType != "" {
+		metrics.jobsTransport[job.Attributes.TransportType]++
+	}
+	if c.cfg.NbuServer.EmitPolicyInfo && job.Attributes.PolicyName != "" {
+		metrics.policies[fmt.Sprintf("%s|%s", job.Attributes.PolicyName, job.Attributes.PolicyType)] = struct{}{}
+	}
+	if c.cfg.Server.JobSubtypes {
+		metrics.jobsSubtypeCount[fmt.Sprintf("%s|%s", job.Attributes.JobType, job.Attributes.JobSubType)]++
+	}
+	metrics.jobsSize[key] += float64(job.Attributes.KilobytesTransferred * 1024)
+	if jobFailed(job.Attributes.Status) {
+		metrics.jobsFailedBytes[job.Attributes.PolicyType] += float64(job.Attributes.KilobytesTransferred * 1024)
+	}
+	// Try starts at 1; the series exists, at 0, as soon as a job of the policy type was seen.
+	metrics.jobsRetries[job.Attributes.PolicyType] += float64(max(job.Attributes.Try-1, 0))
+
+	if job.Attributes.ElapsedTime != "" {
+		elapsed, err := parseElapsed(job.Attributes.ElapsedTime)
+		if err != nil {
+			logging.LogDebug(fmt.Sprintf("Ignoring elapsed time of job %d: %v", job.Attributes.JobID, err))
+		} else {
+			metrics.jobsElapsed[job.Attributes.PolicyType] = max(metrics.jobsElapsed[job.Attributes.PolicyType], elapsed.Seconds())
+			if job.Attributes.JobType == "BACKUP" {
+				metrics.backupBytes[job.Attributes.PolicyType] += float64(job.Attributes.KilobytesTransferred * 1024)
+				metrics.backupSeconds[job.Attributes.PolicyType] += elapsed.Seconds()
+			}
+		}
+	}
+
+	metrics.policyJobs[job.Attributes.PolicyType]++
+	if job.Attributes.Status == 0 {
+		metrics.policySuccesses[job.Attributes.PolicyType]++
+		if job.Attributes.EndTime.After(metrics.lastSuccessEnd[job.Attributes.PolicyType]) {
+			metrics.lastSuccessEnd[job.Attributes.PolicyType] = job.Attributes.EndTime
+		}
+		if job.Attributes.JobType == "BACKUP" {
+			metrics.backedUpClients[job.Attributes.ClientName] = struct{}{}
+		}
+	}
+}
 
-	if jobs.Meta.Pagination.Offset == jobs.Meta.Pagination.Last {
-		return -1, nil
+// selectFields restricts the attributes returned for the resource type to fields, through a
+// JSON:API sparse fieldset, when nbuserver.useFieldSelection is set.
+func (c *nbuClient) selectFields(queryParams map[string]string, resourceType string, fields []string) {
+	if c.cfg.NbuServer.UseFieldSelection {
+		queryParams[fmt.Sprintf(queryParamFields, resourceType)] = strings.Join(fields, ",")
 	}
+}
 
-	return jobs.Meta.Pagination.Next, nil
+// policyAllowed reports whether jobs of the policy are counted, according to nbuserver.policyAllowlist.
+// Every policy is allowed when the allow-list is empty.
+func (c *nbuClient) policyAllowed(policyName string) bool {
+	return len(c.cfg.NbuServer.PolicyAllowlist) == 0 || slices.Contains(c.cfg.NbuServer.PolicyAllowlist, policyName)
 }
 
 // handlePagination iterates over paginated responses and processes them.
-func handlePagination(fetchFunc func(offset int) (int, error)) error {
+// A next offset that does not advance past the current one would loop forever, so it stops
+// the iteration with an error matching ErrPaginationStalled and is counted as an anomaly.
+// When a page fails after others were processed, the error matches ErrPartialResults: the
+// metrics of the processed pages are kept, so that the collection degrades gracefully.
+func (c *nbuClient) handlePagination(fetchFunc func(offset int) (int, error)) error {
 	offset := 0
-	for offset != -1 {
+	for pages := 0; offset != -1; pages++ {
 		nextOffset, err := fetchFunc(offset)
 		if err != nil {
+			if pages > 0 {
+				return fmt.Errorf("%w after %d pages: %w", ErrPartialResults, pages, err)
+			}
 			return err
 		}
+		if nextOffset != -1 && nextOffset <= offset {
+			c.paginationAnomalies.Inc()
+			return fmt.Errorf("%w: next offset %d after offset %d", ErrPaginationStalled, nextOffset, offset)
+		}
 		offset = nextOffset
 	}
 	return nil
 }
 
+// checkPagination warns about a non-empty page of endpoint returned without pagination metadata,
+// as some API versions omit it: the pagination then stops after that page.
+func checkPagination[P comparable](c *nbuClient, endpoint string, items int, pagination P) {
+	var missing P
+	if items == 0 || pagination != missing {
+		return
+	}
+	c.paginationMetadataMissing.WithLabelValues(endpoint).Inc()
+	logging.LogWarning(fmt.Sprintf("The %s response has %d items but no pagination metadata, remaining pages are skipped", endpoint, items))
+}
+
+// fetchJobsConcurrently fetches the first page of jobs, then the remaining pages up to the
+// last offset it announces, with up to concurrency requests at a time.
+// Pages are counted one at a time, so the metric maps are never written concurrently.
+// The first failure cancels the pending requests; as with handlePagination, it matches
+// ErrPartialResults when pages were already processed.
+func (c *nbuClient) fetchJobsConcurrently(ctx context.Context, metrics *nbuMetrics, startTime time.Time, concurrency int) error {
+	first, err := c.fetchJobPage(ctx, metrics, startTime, 0)
+	if err != nil {
+		return err
+	}
+	for _, job := range first.Data {
+		c.countJob(metrics, job)
+	}
+	step := nextJobOffset(first)
+	if step == -1 {
+		return nil
+	}
+	if step <= 0 {
+		c.paginationAnomalies.Inc()
+		return fmt.Errorf("%w: next offset %d after offset 0", ErrPaginationStalled, step)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		pages    = 1
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	offsets := make(chan int)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range offsets {
+				jobs, err := c.fetchJobPage(ctx, metrics, startTime, offset)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+				} else if firstErr == nil {
+					for _, job := range jobs.Data {
+						c.countJob(metrics, job)
+					}
+					pages++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for offset := step; offset <= first.Meta.Pagination.Last; offset += step {
+		select {
+		case offsets <- offset:
+		case <-ctx.Done():
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			mu.Unlock()
+			break send
+		}
+	}
+	close(offsets)
+	wg.Wait()
+
+	if firstErr != nil {
+		return fmt.Errorf("%w after %d pages: %w", ErrPartialResults, pages, firstErr)
+	}
+	return nil
+}
+
 // fetchAllJobs aggregates job statistics by iterating over paginated job data.
-func fetchAllJobs(jobsSize, jobsCount, jobsStatusCount map[string]float64, cfg models.Config) error {
-	client := createHTTPClient()
-	return handlePagination(func(offset int) (int, error) {
-		return fetchJobDetails(client, jobsSize, jobsCount, jobsStatusCount, offset, cfg)
+// The number of job series is then capped to nbuserver.maxJobSeries when it is set.
+// With nbuserver.incrementalJobs, only the jobs ended since the previous collection are
+// fetched, and the summed job metrics are totals since the exporter started. The cap then
+// applies to the jobs of each collection.
+func (c *nbuClient) fetchAllJobs(ctx context.Context, metrics *nbuMetrics) error {
+	interval, err := c.cfg.GetScrapingDuration()
+	if err != nil {
+		return err
+	}
+	now := c.now()
+	startTime := now.Add(-interval).UTC()
+	if c.cfg.NbuServer.IncrementalJobs {
+		c.jobTotals.mu.Lock()
+		defer c.jobTotals.mu.Unlock()
+		if !c.jobTotals.lastEnd.IsZero() {
+			startTime = c.jobTotals.lastEnd.UTC()
+		}
+	}
+
+	if concurrency := c.cfg.NbuServer.PaginationConcurrency; concurrency > 1 {
+		err = c.fetchJobsConcurrently(ctx, metrics, startTime, concurrency)
+	} else {
+		err = c.handlePagination(func(offset int) (int, error) {
+			return c.fetchJobDetails(ctx, metrics, startTime, offset)
+		})
+	}
+	// The series are capped before being added to the totals, so that a series folded into
+	// the other one keeps its earlier jobs and no total ever decreases.
+	metrics.jobsSeriesTruncated = float64(limitJobSeries(metrics, c.cfg.NbuServer.MaxJobSeries))
+	if c.cfg.NbuServer.IncrementalJobs {
+		c.jobTotals.add(metrics, err == nil)
+	}
+	for policyType, end := range metrics.lastSuccessEnd {
+		metrics.policyLastSuccess[policyType] = max(now.Sub(end).Seconds(), 0)
+	}
+	return errors.Join(err, c.fetchOldestActiveJob(ctx, metrics, now), c.fetchQueuedJobs(ctx, metrics))
+}
+
+// fetchOldestActiveJob records how long the oldest active job has been running.
+// Active jobs have not ended yet, so they are queried separately from the lookback filter.
+func (c *nbuClient) fetchOldestActiveJob(ctx context.Context, metrics *nbuMetrics, now time.Time) error {
+	var jobs models.Jobs
+
+	queryParams := map[string]string{
+		queryParamLimit:  "1",
+		queryParamSort:   "startTime",
+		queryParamFilter: "state eq 'ACTIVE'",
+	}
+	c.selectFields(queryParams, "job", jobFields)
+
+	url := buildURL(c.baseURL, c.jobsPath, queryParams)
+
+	if err := c.fetchData(ctx, models.CollectorJobs, url, &jobs); err != nil {
+		return err
+	}
+	metrics.countPage(models.CollectorJobs)
+
+	if len(jobs.Data) == 0 {
+		return nil
+	}
+	started := jobs.Data[0].Attributes.ActiveTryStartTime
+	if started.IsZero() {
+		started = jobs.Data[0].Attributes.StartTime
+	}
+	if started.IsZero() {
+		return nil
+	}
+	metrics.oldestActiveJob = max(now.Sub(started).Seconds(), 0)
+	return nil
+}
+
+// fetchQueuedJobs counts the queued jobs per queue reason.
+// Queued jobs have not ended yet, so they are queried separately from the lookback filter.
+func (c *nbuClient) fetchQueuedJobs(ctx context.Context, metrics *nbuMetrics) error {
+	return c.handlePagination(func(offset int) (int, error) {
+		var jobs models.Jobs
+
+		queryParams := map[string]string{
+			queryParamLimit:  pageLimit,
+			queryParamOffset: fmt.Sprintf("%d", offset),
+			queryParamSort:   "jobId",
+			queryParamFilter: "state eq 'QUEUED'",
+		}
+		c.selectFields(queryParams, "job", jobFields)
+
+		url := buildURL(c.baseURL, c.jobsPath, queryParams)
+
+		if err := c.fetchData(ctx, models.CollectorJobs, url, &jobs); err != nil {
+			return -1, err
+		}
+		metrics.countPage(models.CollectorJobs)
+		checkPagination(c, models.CollectorJobs, len(jobs.Data), jobs.Meta.Pagination)
+
+		for _, job := range jobs.Data {
+			if c.policyAllowed(job.Attributes.PolicyName) {
+				metrics.jobsQueued[queueReasonName(job.Attributes.JobQueueReason)]++
+			}
+		}
+		if len(jobs.Data) == 0 || jobs.Meta.Pagination.Offset >= jobs.Meta.Pagination.Last {
+			return -1, nil
+		}
+		return jobs.Meta.Pagination.Next, nil
 	})
 }
+
+// limitJobSeries keeps the max-1 job series with the most jobs and folds the others into a
+// single series whose labels are all "other". It returns the number of series folded.
+func limitJobSeries(metrics *nbuMetrics, max int) int {
+	if max <= 0 || len(metrics.jobsCount) <= max {
+		return 0
+	}
+
+	keys := make([]string, 0, len(metrics.jobsCount))
+	for key := range metrics.jobsCount {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if metrics.jobsCount[keys[i]] != metrics.jobsCount[keys[j]] {
+			return metrics.jobsCount[keys[i]] > metrics.jobsCount[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	overflow := keys[max-1:]
+	for _, key := range overflow {
+		metrics.jobsCount[otherJobSeries] += metrics.jobsCount[key]
+		metrics.jobsSize[otherJobSeries] += metrics.jobsSize[key]
+		delete(metrics.jobsCount, key)
+		delete(metrics.jobsSize, key)
+	}
+	return len(overflow)
+}
@@ -0,0 +1,983 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// newTestServer starts a fake NetBackup API answering with handler, closed at the end of the test.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testConfig returns a configuration querying the fake NetBackup API over plain HTTP.
+func testConfig(t *testing.T, server *httptest.Server) models.Config {
+	t.Helper()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg models.Config
+	cfg.Server.ScrappingInterval = "1h"
+	cfg.NbuServer.Scheme = serverURL.Scheme
+	cfg.NbuServer.Host = serverURL.Hostname()
+	cfg.NbuServer.Port = serverURL.Port()
+	return cfg
+}
+
+// writeJSON answers body as a NetBackup JSON response.
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", contentType)
+	fmt.Fprint(w, body)
+}
+
+func TestFetchDataReauthenticatesOnUnauthorized(t *testing.T) {
+	var logins, unauthorized int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login":
+			logins++
+			writeJSON(w, `{"token":"fresh-token"}`)
+		case r.Header.Get(headerAuthorization) != "fresh-token":
+			unauthorized++
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			writeJSON(w, `{"data":[{"attributes":{"name":"stu1","storageType":"Disk","storageServerType":"MSDP","freeCapacityBytes":10,"usedCapacityBytes":20}}]}`)
+		}
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.APIKey = "expired-key"
+	cfg.NbuServer.TokenEndpoint = "/login"
+
+	client := newNbuClient(cfg)
+	metrics := newNbuMetrics()
+	if err := client.fetchStorage(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+
+	if logins != 1 || unauthorized != 1 {
+		t.Errorf("logins = %d, unauthorized responses = %d, want 1 and 1", logins, unauthorized)
+	}
+	if client.currentToken() != "fresh-token" {
+		t.Errorf("token = %q, want %q", client.currentToken(), "fresh-token")
+	}
+	if metrics.disks["stu1|MSDP|free"] != 10 {
+		t.Errorf("free capacity = %v, want 10", metrics.disks["stu1|MSDP|free"])
+	}
+}
+
+func TestFetchAllJobsSendsCustomFilter(t *testing.T) {
+	const filter = "startTime gt 2024-01-01T00:00:00Z and jobType eq 'BACKUP'"
+	var rawQuery string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !isStateQuery(r) {
+			rawQuery = r.URL.RawQuery
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.JobFilter = filter
+
+	metrics := newNbuMetrics()
+	if err := newNbuClient(cfg).fetchAllJobs(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchAllJobs() error = %v", err)
+	}
+	if want := "filter=" + url.QueryEscape(filter); !strings.Contains(rawQuery, want) {
+		t.Errorf("query = %q, want it to contain %q", rawQuery, want)
+	}
+}
+
+// isQueuedQuery reports whether the request queries the queued jobs rather than the lookback window.
+func isQueuedQuery(r *http.Request) bool {
+	return r.URL.Query().Get(queryParamFilter) == "state eq 'QUEUED'"
+}
+
+// isStateQuery reports whether the request queries the active or queued jobs rather than
+// the lookback window.
+func isStateQuery(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Query().Get(queryParamFilter), "state eq ")
+}
+
+// isLookbackQuery reports whether r is the jobs query filtered on the lookback window,
+// as opposed to the active and queued jobs queries.
+func isLookbackQuery(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Query().Get(queryParamFilter), "endTime gt")
+}
+
+// writeJobPage answers the page of one backup job at offset, the last page being at last.
+func writeJobPage(w http.ResponseWriter, r *http.Request, last int) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get(queryParamOffset))
+	writeJSON(w, fmt.Sprintf(`{"data":[{"attributes":{"jobId":%d,"jobType":"BACKUP","policyType":"Standard","status":0,"kilobytesTransferred":1}}],
+		"meta":{"pagination":{"offset":%d,"next":%d,"last":%d,"limit":1,"count":%d}}}`, offset+1, offset, offset+1, last, last+1))
+}
+
+func TestHandlePaginationWrapsLaterPageErrors(t *testing.T) {
+	client := newNbuClient(models.Config{})
+	failure := errors.New("page failed")
+	for _, tt := range []struct {
+		failAt  int
+		partial bool
+	}{
+		{failAt: 0},
+		{failAt: 2, partial: true},
+	} {
+		err := client.handlePagination(func(offset int) (int, error) {
+			if offset == tt.failAt {
+				return 0, failure
+			}
+			return offset + 1, nil
+		})
+		if !errors.Is(err, failure) || errors.Is(err, ErrPartialResults) != tt.partial {
+			t.Errorf("handlePagination() failing at offset %d error = %v, want the page error, partial %t", tt.failAt, err, tt.partial)
+		}
+	}
+}
+
+func TestCollectCountsPagesPerScrape(t *testing.T) {
+	const last = 2
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultJobsPath || isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		writeJobPage(w, r, last)
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(testConfig(t, server)))
+
+	// The second scrape must report its own pages, not the sum of both.
+	for range 2 {
+		pages := gatherSeries(t, registry, "nbu_api_pages_fetched")
+		// The jobs pages include the single pages of active and queued jobs.
+		if want := map[string]float64{"jobs": last + 3, "storage": 1}; !maps.Equal(pages, want) {
+			t.Errorf("pages fetched = %v, want %v", pages, want)
+		}
+	}
+}
+
+func TestCollectExposesSuccessRatio(t *testing.T) {
+	jobs := []struct {
+		policyType string
+		status     int
+	}{
+		{"Standard", 0},
+		{"Standard", 0},
+		{"Standard", 1},
+		{"Standard", 150},
+		{"VMware", 0},
+	}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != defaultJobsPath || isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get(queryParamOffset))
+		job := jobs[offset]
+		writeJSON(w, fmt.Sprintf(`{"data":[{"attributes":{"jobType":"BACKUP","policyType":%q,"status":%d}}],
+			"meta":{"pagination":{"offset":%d,"next":%d,"last":%d}}}`, job.policyType, job.status, offset, offset+1, len(jobs)-1))
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(testConfig(t, server)))
+
+	ratios := gatherSeries(t, registry, "nbu_jobs_success_ratio")
+	if want := map[string]float64{"Standard": 0.5, "VMware": 1}; !maps.Equal(ratios, want) {
+		t.Errorf("success ratios = %v, want %v", ratios, want)
+	}
+}
+
+func TestFetchUsesConfiguredPaths(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		writeJSON(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.URI = "/proxy/netbackup"
+	cfg.NbuServer.JobsPath = "/custom/jobs"
+	cfg.NbuServer.StoragePath = "/custom/storage"
+
+	if _, err := NewNbuCollector(cfg).gather(context.Background()); err != nil {
+		t.Fatalf("gather() error = %v", err)
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+	if want := []string{"/proxy/netbackup/custom/jobs", "/proxy/netbackup/custom/storage"}; !slices.Equal(paths, want) {
+		t.Errorf("requested paths = %q, want %q", paths, want)
+	}
+}
+
+func TestLimitJobSeriesFoldsSmallestSeries(t *testing.T) {
+	metrics := newNbuMetrics()
+	for i := range 10 {
+		key := fmt.Sprintf("BACKUP|policy%d|0", i)
+		metrics.jobsCount[key] = float64(i + 1)
+		metrics.jobsSize[key] = float64(100 * (i + 1))
+	}
+
+	if folded := limitJobSeries(metrics, 4); folded != 7 {
+		t.Errorf("limitJobSeries() = %d, want 7", folded)
+	}
+	want := map[string]float64{
+		"BACKUP|policy9|0": 10,
+		"BACKUP|policy8|0": 9,
+		"BACKUP|policy7|0": 8,
+		otherJobSeries:     1 + 2 + 3 + 4 + 5 + 6 + 7,
+	}
+	if !maps.Equal(metrics.jobsCount, want) {
+		t.Errorf("jobs count = %v, want %v", metrics.jobsCount, want)
+	}
+	if got := metrics.jobsSize[otherJobSeries]; got != 100*28 {
+		t.Errorf("other jobs size = %v, want %v", got, 100*28)
+	}
+
+	if folded := limitJobSeries(metrics, 0); folded != 0 || len(metrics.jobsCount) != 4 {
+		t.Errorf("limitJobSeries() without a limit folded %d series, want none", folded)
+	}
+}
+
+func TestFetchGoesThroughConfiguredProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.Host
+		writeJSON(w, `{"data":[]}`)
+	})
+	var cfg models.Config
+	cfg.NbuServer.Scheme = "http"
+	cfg.NbuServer.Host = "nbu.example.invalid"
+	cfg.NbuServer.Port = "1556"
+	cfg.NbuServer.ProxyURL = proxy.URL
+
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	if proxiedHost != "nbu.example.invalid:1556" {
+		t.Errorf("proxied host = %q, want the NetBackup server", proxiedHost)
+	}
+}
+
+func TestFetchAllJobsCountsQueuedJobs(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get(queryParamFilter) == "state eq 'QUEUED'" {
+			writeJSON(w, `{"data":[{"attributes":{"jobType":"BACKUP","state":"QUEUED","jobQueueReason":2}}],
+				"meta":{"pagination":{"offset":0,"last":0,"count":1}}}`)
+			return
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+
+	metrics := newNbuMetrics()
+	if err := newNbuClient(testConfig(t, server)).fetchAllJobs(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchAllJobs() error = %v", err)
+	}
+	if got := metrics.jobsQueued["drives_in_use"]; got != 1 {
+		t.Errorf("queued jobs for drives_in_use = %v, want 1 (all: %v)", got, metrics.jobsQueued)
+	}
+}
+
+func TestFetchAllJobsCountsOnlyAllowedPolicies(t *testing.T) {
+	policies := []string{"gold", "silver", "gold", "bronze"}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if isQueuedQuery(r) {
+			writeJSON(w, `{"data":[{"attributes":{"policyName":"gold","jobQueueReason":2}},{"attributes":{"policyName":"silver","jobQueueReason":2}}],
+				"meta":{"pagination":{"offset":0,"last":0}}}`)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get(queryParamOffset))
+		writeJSON(w, fmt.Sprintf(`{"data":[{"attributes":{"jobType":"BACKUP","policyName":%q,"policyType":"Standard","status":0}}],
+			"meta":{"pagination":{"offset":%d,"next":%d,"last":%d}}}`, policies[offset], offset, offset+1, len(policies)-1))
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.PolicyAllowlist = []string{"gold"}
+
+	metrics := newNbuMetrics()
+	if err := newNbuClient(cfg).fetchAllJobs(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchAllJobs() error = %v", err)
+	}
+	if got := metrics.jobsCount["BACKUP|Standard|0"]; got != 2 {
+		t.Errorf("counted jobs = %v, want the 2 jobs of the gold policy", got)
+	}
+	if got := metrics.jobsQueued["drives_in_use"]; got != 1 {
+		t.Errorf("queued jobs = %v, want the 1 queued job of the gold policy", got)
+	}
+}
+
+func TestFetchAllJobsSkipsExcludedStatuses(t *testing.T) {
+	statuses := []int{0, 1, 150, 1}
+	end := time.Now().Add(-time.Minute).UTC()
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get(queryParamOffset))
+		// The last job, excluded, is the latest to end.
+		writeJSON(w, fmt.Sprintf(`{"data":[{"attributes":{"jobType":"BACKUP","policyType":"Standard","status":%d,"endTime":%q}}],
+			"meta":{"pagination":{"offset":%d,"next":%d,"last":%d}}}`,
+			statuses[offset], end.Add(time.Duration(offset)*time.Second).Format(time.RFC3339), offset, offset+1, len(statuses)-1))
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.ExcludeStatuses = []int{1}
+
+	metrics := newNbuMetrics()
+	if err := newNbuClient(cfg).fetchAllJobs(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchAllJobs() error = %v", err)
+	}
+	if want := map[string]float64{"BACKUP|Standard|0": 1, "BACKUP|Standard|150": 1}; !maps.Equal(metrics.jobsCount, want) {
+		t.Errorf("counted jobs = %v, want %v", metrics.jobsCount, want)
+	}
+	if want := end.Add(3 * time.Second).Truncate(time.Second); !metrics.lastJobEnd.Equal(want) {
+		t.Errorf("latest job end = %v, want %v of the excluded job", metrics.lastJobEnd, want)
+	}
+}
+
+func TestFetchDataRejectsOversizedResponse(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentType, contentType)
+		// Stream far more than the limit, as a misbehaving endpoint would.
+		chunk := strings.Repeat(" ", 1024)
+		fmt.Fprint(w, `{"data":[`)
+		for range 1024 {
+			fmt.Fprint(w, chunk)
+		}
+		fmt.Fprint(w, `]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.MaxResponseBytes = 4096
+
+	err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics())
+	if err == nil || !strings.Contains(err.Error(), "exceeds maxResponseBytes (4096 bytes)") {
+		t.Errorf("fetchStorage() error = %v, want the response size limit error", err)
+	}
+
+	cfg.NbuServer.MaxResponseBytes = 0
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Errorf("fetchStorage() without a limit error = %v", err)
+	}
+}
+
+func TestFetchAppliesEndpointTimeouts(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		writeJSON(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.Timeouts.Jobs = "2s"
+	cfg.NbuServer.Timeouts.Storage = "50ms"
+	client := newNbuClient(cfg)
+
+	if err := client.fetchAllJobs(context.Background(), newNbuMetrics()); err != nil {
+		t.Errorf("fetchAllJobs() within its timeout error = %v", err)
+	}
+	if err := client.fetchStorage(context.Background(), newNbuMetrics()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("fetchStorage() error = %v, want the storage timeout to expire", err)
+	}
+}
+
+func TestFetchDataRetriesAfterRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set(headerRetryAfter, "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	client := newNbuClient(testConfig(t, server))
+
+	start := time.Now()
+	if err := client.fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the 1s Retry-After delay", elapsed)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(client.rateLimited); got != 1 {
+		t.Errorf("nbu_api_rate_limited_total = %v, want 1", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", want: defaultRetryAfter, wantOK: true},
+		{value: "3", want: 3 * time.Second, wantOK: true},
+		{value: now.Add(5 * time.Second).Format(http.TimeFormat), want: 5 * time.Second, wantOK: true},
+		{value: now.Add(-5 * time.Second).Format(http.TimeFormat), want: 0, wantOK: true},
+		{value: "soon"},
+	} {
+		got, ok := retryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("retryAfter(%q) = %v, %t, want %v, %t", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFetchDataSendsExtraHeaders(t *testing.T) {
+	var mu sync.Mutex
+	gateways := map[string]string{}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gateways[r.URL.Path] = r.Header.Get("X-Gateway-Key")
+		mu.Unlock()
+		if r.URL.Path == "/login" {
+			writeJSON(w, `{"token":"session-token"}`)
+			return
+		}
+		if r.Header.Get(headerAuthorization) != "session-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.TokenEndpoint = "/login"
+	cfg.NbuServer.ExtraHeaders = map[string]string{"X-Gateway-Key": "secret"}
+
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+
+	want := map[string]string{"/login": "secret", defaultStoragePath: "secret"}
+	if !maps.Equal(gateways, want) {
+		t.Errorf("X-Gateway-Key per path = %v, want %v", gateways, want)
+	}
+}
+
+func TestGetHeadersAcceptFormat(t *testing.T) {
+	for _, tt := range []struct {
+		apiVersion, acceptFormat, want string
+	}{
+		{want: contentType},
+		{apiVersion: "8.0", want: "application/vnd.netbackup+json;version=8.0"},
+		{apiVersion: "8.0", acceptFormat: models.AcceptFormatVersioned, want: "application/vnd.netbackup+json;version=8.0"},
+		{apiVersion: "8.0", acceptFormat: models.AcceptFormatPlain, want: contentType},
+	} {
+		var cfg models.Config
+		cfg.NbuServer.APIVersion = tt.apiVersion
+		cfg.NbuServer.AcceptFormat = tt.acceptFormat
+		if got := getHeaders(cfg, "")[headerAccept]; got != tt.want {
+			t.Errorf("Accept with apiVersion %q and acceptFormat %q = %q, want %q", tt.apiVersion, tt.acceptFormat, got, tt.want)
+		}
+	}
+}
+
+func TestFetchEnforcesMinTLSVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[]}`)
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	cfg := testConfig(t, server)
+
+	cfg.NbuServer.MinTLSVersion = "1.2"
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Errorf("fetchStorage() with minTLSVersion 1.2 error = %v", err)
+	}
+	cfg.NbuServer.MinTLSVersion = "1.3"
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err == nil {
+		t.Error("fetchStorage() with minTLSVersion 1.3 against a TLS 1.2 server succeeded, want a handshake error")
+	}
+}
+
+func TestFetchDataSendsOneRequestIDPerFetch(t *testing.T) {
+	var ids []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Correlation-ID"))
+		if len(ids) == 1 {
+			w.Header().Set(headerRetryAfter, "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.RequestIDHeader = "X-Correlation-ID"
+
+	err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics())
+	if len(ids) != 2 || ids[0] == "" || ids[0] != ids[1] {
+		t.Fatalf("request IDs = %q, want the same ID on the request and its retry", ids)
+	}
+	if err == nil || !strings.Contains(err.Error(), ids[0]) {
+		t.Errorf("fetchStorage() error = %v, want it to include request ID %s", err, ids[0])
+	}
+}
+
+func TestFetchFunctionsHandleNullData(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data": null}`)
+	})
+	client := newNbuClient(testConfig(t, server))
+
+	for name, fetch := range map[string]func(context.Context, *nbuMetrics) error{
+		models.CollectorStorage:      client.fetchStorage,
+		models.CollectorJobs:         client.fetchAllJobs,
+		models.CollectorMediaServers: client.fetchMediaServers,
+		models.CollectorImages:       client.fetchImages,
+		models.CollectorSLP:          client.fetchSLPStatus,
+		models.CollectorDiskPools:    client.fetchDiskPools,
+		models.CollectorVMware:       client.fetchVMwareProtection,
+		models.CollectorAudit:        client.fetchAuditEvents,
+		models.CollectorClients:      client.fetchClients,
+		models.CollectorCatalog:      client.fetchCatalogBackup,
+	} {
+		t.Run(name, func(t *testing.T) {
+			metrics := newNbuMetrics()
+			if err := fetch(context.Background(), metrics); err != nil {
+				t.Fatalf("fetch error = %v", err)
+			}
+			for _, values := range []map[string]float64{
+				metrics.storageUnits, metrics.disks, metrics.storageWorm, metrics.jobsCount, metrics.jobsQueued, metrics.mediaServers,
+				metrics.imagesCount, metrics.slpBacklogBytes, metrics.diskPools, metrics.auditEvents,
+			} {
+				if len(values) != 0 {
+					t.Errorf("metrics = %v, want none", values)
+				}
+			}
+			if metrics.oldestActiveJob != 0 || metrics.vmsProtected != 0 || metrics.registeredClients != 0 || metrics.catalogBackupFound {
+				t.Errorf("scalar metrics set from null data")
+			}
+		})
+	}
+}
+
+func TestFetchClientsCountsDistinctHosts(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != hostsPath {
+			t.Errorf("request path = %q, want %q", r.URL.Path, hostsPath)
+		}
+		writeJSON(w, `{"hosts":[
+			{"uuid":"1","hostName":"client1","osType":"Linux"},
+			{"uuid":"2","hostName":"client2","osType":"Windows"},
+			{"uuid":"3","hostName":"client1","osType":"Linux"}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorClients}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+
+	if got := gatherValues(t, registry)["nbu_registered_clients"]; got != 2 {
+		t.Errorf("nbu_registered_clients = %v, want 2", got)
+	}
+}
+
+func TestFetchCatalogBackupReportsLastSuccess(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	var found atomic.Bool
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if want := "policyType eq 'NBU_CATALOG' and status eq 0"; query.Get(queryParamFilter) != want {
+			t.Errorf("filter = %q, want %q", query.Get(queryParamFilter), want)
+		}
+		if query.Get(queryParamSort) != "-endTime" || query.Get(queryParamLimit) != "1" {
+			t.Errorf("sort = %q and limit = %q, want the latest job only", query.Get(queryParamSort), query.Get(queryParamLimit))
+		}
+		if !found.Load() {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		writeJSON(w, `{"data":[{"attributes":{"policyType":"NBU_CATALOG","status":0,"endTime":"2026-10-15T10:00:00Z"}}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorCatalog}
+	collector := NewNbuCollector(cfg)
+	collector.client.now = func() time.Time { return now }
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	if _, ok := gatherValues(t, registry)[models.MetricCatalogBackupLastSuccessSeconds]; ok {
+		t.Errorf("%s collected without any catalog backup, want it absent", models.MetricCatalogBackupLastSuccessSeconds)
+	}
+
+	found.Store(true)
+	if got := gatherValues(t, registry)[models.MetricCatalogBackupLastSuccessSeconds]; got != 7200 {
+		t.Errorf("%s = %v, want 7200", models.MetricCatalogBackupLastSuccessSeconds, got)
+	}
+}
+
+func TestFetchStorageKeepsDuplicateNames(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[
+			{"id":"stu-1","attributes":{"name":"disk","storageType":"DISK","storageServerType":"PureDisk","freeCapacityBytes":1,"usedCapacityBytes":2}},
+			{"id":"stu-2","attributes":{"name":"disk","storageType":"DISK","storageServerType":"PureDisk","freeCapacityBytes":3,"usedCapacityBytes":4}}]}`)
+	})
+	client := newNbuClient(testConfig(t, server))
+
+	metrics := newNbuMetrics()
+	if err := client.fetchStorage(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	want := map[string]float64{
+		"disk|PureDisk|free": 1, "disk|PureDisk|used": 2,
+		"disk#stu-2|PureDisk|free": 3, "disk#stu-2|PureDisk|used": 4,
+	}
+	if !maps.Equal(metrics.disks, want) {
+		t.Errorf("disks = %v, want %v", metrics.disks, want)
+	}
+	if got := testutil.ToFloat64(client.storageDuplicates); got != 1 {
+		t.Errorf("nbu_storage_duplicate_keys_total = %v, want 1", got)
+	}
+}
+
+func TestFetchStorageReportsWorm(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[
+			{"id":"1","attributes":{"name":"immutable","storageType":"DISK","storageServerType":"PureDisk","wormCapable":true,"useWorm":true}},
+			{"id":"2","attributes":{"name":"plain","storageType":"DISK","storageServerType":"PureDisk","wormCapable":true}},
+			{"id":"3","attributes":{"name":"tape","storageType":"Tape","useWorm":true}}]}`)
+	})
+	client := newNbuClient(testConfig(t, server))
+
+	metrics := newNbuMetrics()
+	if err := client.fetchStorage(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	if want := map[string]float64{"immutable": 1, "plain": 0}; !maps.Equal(metrics.storageWorm, want) {
+		t.Errorf("storageWorm = %v, want %v", metrics.storageWorm, want)
+	}
+}
+
+func TestFetchStorageReportsZeroForTypesSeenBefore(t *testing.T) {
+	body := `{"data":[
+		{"id":"1","attributes":{"name":"disk","storageType":"DISK","storageServerType":"PureDisk"}},
+		{"id":"2","attributes":{"name":"cloud","storageType":"CLOUD","storageServerType":"AZURE"}},
+		{"id":"3","attributes":{"name":"tape","storageType":"Tape"}}]}`
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, body)
+	})
+	client := newNbuClient(testConfig(t, server))
+
+	metrics := newNbuMetrics()
+	if err := client.fetchStorage(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	want := map[string]float64{"DISK": 1, "CLOUD": 1, "Tape": 1}
+	if !maps.Equal(metrics.storageUnits, want) {
+		t.Errorf("storage units = %v, want %v", metrics.storageUnits, want)
+	}
+
+	body = `{"data": null}`
+	metrics = newNbuMetrics()
+	if err := client.fetchStorage(context.Background(), metrics); err != nil {
+		t.Fatalf("fetchStorage() error = %v", err)
+	}
+	want = map[string]float64{"DISK": 0, "CLOUD": 0, "Tape": 0}
+	if !maps.Equal(metrics.storageUnits, want) {
+		t.Errorf("storage units after empty response = %v, want %v", metrics.storageUnits, want)
+	}
+}
+
+func TestFetchAllJobsPaginationConcurrency(t *testing.T) {
+	const last = 9
+	for _, concurrency := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			var inFlight, maxInFlight atomic.Int32
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if !isLookbackQuery(r) {
+					writeJSON(w, `{"data":[]}`)
+					return
+				}
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for current := maxInFlight.Load(); n > current && !maxInFlight.CompareAndSwap(current, n); current = maxInFlight.Load() {
+				}
+				// With concurrency, hold the request until another one overlaps it, or long enough to tell.
+				for deadline := time.Now().Add(200 * time.Millisecond); concurrency > 1 && inFlight.Load() < 2 && time.Now().Before(deadline); {
+					time.Sleep(time.Millisecond)
+				}
+				writeJobPage(w, r, last)
+			})
+			cfg := testConfig(t, server)
+			cfg.NbuServer.PaginationConcurrency = concurrency
+
+			metrics := newNbuMetrics()
+			if err := newNbuClient(cfg).fetchAllJobs(context.Background(), metrics); err != nil {
+				t.Fatalf("fetchAllJobs() error = %v", err)
+			}
+			if got := metrics.jobsCount["BACKUP|Standard|0"]; got != last+1 {
+				t.Errorf("jobs counted = %v, want %d", got, last+1)
+			}
+			if got := metrics.jobsSize["BACKUP|Standard|0"]; got != (last+1)*1024 {
+				t.Errorf("job bytes = %v, want %d", got, (last+1)*1024)
+			}
+			if concurrency > 1 && maxInFlight.Load() < 2 {
+				t.Errorf("at most %d request in flight, want overlapping requests", maxInFlight.Load())
+			}
+			if concurrency <= 1 && maxInFlight.Load() != 1 {
+				t.Errorf("%d requests in flight, want sequential requests", maxInFlight.Load())
+			}
+		})
+	}
+}
+
+func TestFetchAllJobsConcurrentPageFailure(t *testing.T) {
+	const last, failing = 9, 5
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !isLookbackQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		if r.URL.Query().Get(queryParamOffset) == strconv.Itoa(failing) {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeJobPage(w, r, last)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.PaginationConcurrency = 3
+
+	metrics := newNbuMetrics()
+	err := newNbuClient(cfg).fetchAllJobs(context.Background(), metrics)
+	if !errors.Is(err, ErrPartialResults) {
+		t.Fatalf("fetchAllJobs() error = %v, want ErrPartialResults", err)
+	}
+	if got := metrics.jobsCount["BACKUP|Standard|0"]; got < 1 || got > last {
+		t.Errorf("jobs counted = %v, want the pages fetched before the failure", got)
+	}
+}
+
+func TestFetchAllJobsOverlappingCollections(t *testing.T) {
+	const last = 19
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !isLookbackQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		writeJobPage(w, r, last)
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.PaginationConcurrency = 4
+	client := newNbuClient(cfg)
+
+	results := make([]*nbuMetrics, 3)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = newNbuMetrics()
+			errs[i] = client.fetchAllJobs(context.Background(), results[i])
+		}()
+	}
+	wg.Wait()
+
+	for i, metrics := range results {
+		if errs[i] != nil {
+			t.Fatalf("collection %d: fetchAllJobs() error = %v", i, errs[i])
+		}
+		if got := metrics.jobsCount["BACKUP|Standard|0"]; got != last+1 {
+			t.Errorf("collection %d: jobs counted = %v, want %d", i, got, last+1)
+		}
+	}
+}
+
+func TestHandlePaginationStopsOnRepeatedNextOffset(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		requests.Add(1)
+		// Every page claims the next one is at offset 1, as if the server ignored the offset.
+		writeJSON(w, `{"data":[{"attributes":{"jobType":"BACKUP","policyType":"Standard"}}],
+			"meta":{"pagination":{"offset":1,"next":1,"last":5}}}`)
+	})
+
+	for name, fetch := range map[string]func(*nbuClient, context.Context, *nbuMetrics) error{
+		models.CollectorJobs:   (*nbuClient).fetchAllJobs,
+		models.CollectorImages: (*nbuClient).fetchImages,
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			client := newNbuClient(testConfig(t, server))
+
+			err := fetch(client, context.Background(), newNbuMetrics())
+			if !errors.Is(err, ErrPaginationStalled) {
+				t.Fatalf("fetch error = %v, want ErrPaginationStalled", err)
+			}
+			if got := requests.Load(); got != 2 {
+				t.Errorf("pages requested = %d, want 2", got)
+			}
+			if got := testutil.ToFloat64(client.paginationAnomalies); got != 1 {
+				t.Errorf("nbu_pagination_anomalies_total = %v, want 1", got)
+			}
+		})
+	}
+}
+
+func TestPaginationMetadataMissingIsCounted(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		requests.Add(1)
+		// The page has items but no meta block at all.
+		writeJSON(w, `{"data":[{"attributes":{"jobType":"BACKUP","policyType":"Standard","slpName":"slp1"}}]}`)
+	})
+
+	for name, fetch := range map[string]func(*nbuClient, context.Context, *nbuMetrics) error{
+		models.CollectorJobs: (*nbuClient).fetchAllJobs,
+		models.CollectorSLP:  (*nbuClient).fetchSLPStatus,
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			client := newNbuClient(testConfig(t, server))
+
+			if err := fetch(client, context.Background(), newNbuMetrics()); err != nil {
+				t.Fatalf("fetch error = %v", err)
+			}
+			if got := requests.Load(); got != 1 {
+				t.Errorf("pages requested = %d, want 1", got)
+			}
+			if got := testutil.ToFloat64(client.paginationMetadataMissing.WithLabelValues(name)); got != 1 {
+				t.Errorf("nbu_pagination_metadata_missing_total{endpoint=%q} = %v, want 1", name, got)
+			}
+		})
+	}
+}
+
+func TestFetchDataDecompressesGzipResponses(t *testing.T) {
+	const body = `{"data":[{"attributes":{"name":"stu1","storageType":"Disk","storageServerType":"MSDP","freeCapacityBytes":10}}]}`
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentType, contentType)
+		w.Header().Set(headerContentEncoding, "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, body)
+		// Pad the decompressed body well beyond the compressed size.
+		fmt.Fprint(gz, strings.Repeat(" ", 64*1024))
+		gz.Close()
+	})
+
+	for name, extraHeaders := range map[string]map[string]string{
+		"transport":         nil,
+		"explicit encoding": {"Accept-Encoding": "gzip"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := testConfig(t, server)
+			cfg.NbuServer.ExtraHeaders = extraHeaders
+			metrics := newNbuMetrics()
+			if err := newNbuClient(cfg).fetchStorage(context.Background(), metrics); err != nil {
+				t.Fatalf("fetchStorage() error = %v", err)
+			}
+			if metrics.disks["stu1|MSDP|free"] != 10 {
+				t.Errorf("free capacity = %v, want 10", metrics.disks["stu1|MSDP|free"])
+			}
+
+			cfg.NbuServer.MaxResponseBytes = 4096
+			if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err == nil {
+				t.Error("fetchStorage() succeeded, want the limit to apply to the decompressed body")
+			}
+		})
+	}
+}
+
+func TestFetchVerifiesCertificateWhenConfigured(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[]}`)
+	}))
+	t.Cleanup(server.Close)
+	cfg := testConfig(t, server)
+
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Errorf("fetchStorage() skipping verification error = %v", err)
+	}
+	verify := false
+	cfg.NbuServer.InsecureSkipVerify = &verify
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err == nil {
+		t.Error("fetchStorage() verifying a self-signed certificate succeeded, want an error")
+	}
+}
+
+func TestFetchDataAcceptsConfiguredContentTypes(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headerContentType, "application/hal+json; charset=utf-8")
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err == nil {
+		t.Error("fetchStorage() accepted application/hal+json with the default content types")
+	}
+	cfg.NbuServer.AcceptedContentTypes = []string{"application/hal+json"}
+	if err := newNbuClient(cfg).fetchStorage(context.Background(), newNbuMetrics()); err != nil {
+		t.Errorf("fetchStorage() with application/hal+json accepted error = %v", err)
+	}
+}
+
+func TestFetchRequestsSelectedFields(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			param, fields := "fields[job]", jobFields
+			if r.URL.Path == defaultStoragePath {
+				param, fields = "fields[storageUnit]", storageUnitFields
+			}
+			got, want := r.URL.Query().Get(param), ""
+			if enabled {
+				want = strings.Join(fields, ",")
+			}
+			if got != want {
+				t.Errorf("useFieldSelection %t: %s %s = %q, want %q", enabled, r.URL.Path, param, got, want)
+			}
+			// The trimmed fixtures only carry the selected attributes.
+			if r.URL.Path == defaultStoragePath {
+				writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","storageServerType":"PureDisk","freeCapacityBytes":5,"usedCapacityBytes":7}}]}`)
+				return
+			}
+			if isStateQuery(r) {
+				writeJSON(w, `{"data":[]}`)
+				return
+			}
+			writeJSON(w, `{"data":[{"attributes":{"jobId":1,"jobType":"BACKUP","policyType":"Standard","status":0,"kilobytesTransferred":2}}]}`)
+		})
+		cfg := testConfig(t, server)
+		cfg.NbuServer.UseFieldSelection = enabled
+		client := newNbuClient(cfg)
+
+		metrics := newNbuMetrics()
+		if err := errors.Join(client.fetchStorage(context.Background(), metrics), client.fetchAllJobs(context.Background(), metrics)); err != nil {
+			t.Fatalf("useFieldSelection %t: fetch error = %v", enabled, err)
+		}
+		if got := metrics.disks["disk|PureDisk|free"]; got != 5 {
+			t.Errorf("useFieldSelection %t: free bytes = %v, want 5", enabled, got)
+		}
+		if got := metrics.jobsCount["BACKUP|Standard|0"]; got != 1 {
+			t.Errorf("useFieldSelection %t: jobs count = %v (all: %v), want 1", enabled, got, metrics.jobsCount)
+		}
+	}
+}
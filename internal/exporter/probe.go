@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+// ProbeResult describes the outcome of querying one endpoint with one API version.
+type ProbeResult struct {
+	Version     string
+	Endpoint    string
+	Status      int
+	ContentType string
+	Err         error
+	// JSON reports whether ContentType is one of the accepted media types.
+	JSON bool
+}
+
+// OK reports whether the endpoint answered successfully with JSON.
+func (r ProbeResult) OK() bool {
+	return r.Err == nil && r.Status == http.StatusOK && r.JSON
+}
+
+// Probe queries every known endpoint with every supported API version and reports how the
+// server answered. It helps diagnosing version mismatches and misconfigured URLs.
+func Probe(cfg models.Config) []ProbeResult {
+	client := newNbuClient(cfg)
+	endpoints := []struct {
+		name string
+		path string
+	}{
+		{"jobs", client.jobsPath},
+		{"storage", client.storagePath},
+	}
+
+	var results []ProbeResult
+	for _, version := range probeVersions(cfg) {
+		versionCfg := cfg
+		versionCfg.NbuServer.APIVersion = version
+		for _, endpoint := range endpoints {
+			result := ProbeResult{Version: version, Endpoint: endpoint.name}
+			url := buildURL(client.baseURL, endpoint.path, map[string]string{queryParamLimit: "1"})
+			resp, err := client.client.R().
+				SetHeaders(getHeaders(versionCfg, client.currentToken())).
+				Get(url)
+			if err != nil {
+				result.Err = err
+			} else {
+				result.Status = resp.StatusCode()
+				result.ContentType = resp.Header().Get(headerContentType)
+				result.JSON = client.isJSONContentType(result.ContentType)
+				if result.Status == http.StatusNotAcceptable {
+					result.Err = &APIVersionError{Version: version, URL: url}
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// probeVersions returns the API versions to probe: the configured one, when it is not
+// already listed, followed by the supported versions.
+func probeVersions(cfg models.Config) []string {
+	configured := cfg.NbuServer.APIVersion
+	if configured == "" || slices.Contains(models.SupportedAPIVersions, configured) {
+		return models.SupportedAPIVersions
+	}
+	return append([]string{configured}, models.SupportedAPIVersions...)
+}
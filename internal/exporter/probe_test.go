@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestProbeReportsHTMLAndJSONEndpoints(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// Only the jobs endpoint with API version 13.0 answers JSON, like a server behind a web portal.
+		if r.URL.Path == defaultJobsPath && strings.Contains(r.Header.Get(headerAccept), "version=13.0") {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		w.Header().Set(headerContentType, "text/html")
+		fmt.Fprint(w, "<html></html>")
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.APIVersion = "11.0"
+
+	results := Probe(cfg)
+	if want := 2 * 4; len(results) != want {
+		t.Fatalf("Probe() returned %d results, want %d for 4 versions and 2 endpoints", len(results), want)
+	}
+	if results[0].Version != "11.0" {
+		t.Errorf("first probed version = %s, want the configured 11.0", results[0].Version)
+	}
+	for _, result := range results {
+		wantOK := result.Version == "13.0" && result.Endpoint == "jobs"
+		if result.OK() != wantOK {
+			t.Errorf("%s %s OK() = %t (status %d, Content-Type %q), want %t", result.Version, result.Endpoint, result.OK(), result.Status, result.ContentType, wantOK)
+		}
+	}
+}
@@ -1,51 +1,580 @@
 package exporter
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fjacquet/nbu_exporter/internal/logging"
 	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/fjacquet/nbu_exporter/internal/version"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// nbuMetrics holds the values gathered from NetBackup during one collection.
+// Each fetch writes to its own maps; maps shared between concurrent fetches are guarded by mu.
+type nbuMetrics struct {
+	mu                sync.Mutex
+	up                bool
+	pagesFetched      map[string]float64
+	disks             map[string]float64
+	storageWorm       map[string]float64
+	storageUnits      map[string]float64
+	jobsSize          map[string]float64
+	jobsCount         map[string]float64
+	jobsStatusCount   map[string]float64
+	mediaServers      map[string]float64
+	policyJobs        map[string]float64
+	policySuccesses   map[string]float64
+	jobsQueued        map[string]float64
+	imagesCount       map[string]float64
+	imagesBytes       map[string]float64
+	backedUpClients   map[string]struct{}
+	jobsElapsed       map[string]float64
+	jobsSubtypeCount  map[string]float64
+	jobsScheduleCount map[string]float64
+	jobsFailedBytes   map[string]float64
+	// jobsRetries sums the tries beyond the first of each job per policy type.
+	jobsRetries map[string]float64
+	// jobsTransport counts the jobs per transport type, for the jobs reporting one.
+	jobsTransport map[string]float64
+	// policies holds the distinct policy name and type pairs seen in the jobs.
+	policies map[string]struct{}
+	// lastSuccessEnd is the end time of the latest successful job per policy type, and
+	// policyLastSuccess the time in seconds elapsed since then.
+	lastSuccessEnd    map[string]time.Time
+	policyLastSuccess map[string]float64
+	// lastJobEnd is the latest end time of the fetched jobs.
+	lastJobEnd time.Time
+	// backupBytes and backupSeconds sum the bytes and elapsed time of backup jobs with a known elapsed time.
+	backupBytes         map[string]float64
+	backupSeconds       map[string]float64
+	diskPools           map[string]float64
+	auditEvents         map[string]float64
+	slpBacklogBytes     map[string]float64
+	slpIncompleteImages map[string]float64
+	vmsProtected        float64
+	vmsUnprotected      float64
+	registeredClients   float64
+	// oldestActiveJob is the age in seconds of the longest-running active job.
+	oldestActiveJob float64
+	// catalogBackupAge is the time in seconds since the last successful catalog backup ended,
+	// when catalogBackupFound is set.
+	catalogBackupAge   float64
+	catalogBackupFound bool
+	// jobsSeriesTruncated is the number of job series folded into the "other" series.
+	jobsSeriesTruncated float64
+}
+
+// newNbuMetrics returns an empty set of metric maps ready to be filled by the fetch functions.
+func newNbuMetrics() *nbuMetrics {
+	return &nbuMetrics{
+		pagesFetched:        make(map[string]float64),
+		disks:               make(map[string]float64),
+		storageWorm:         make(map[string]float64),
+		storageUnits:        make(map[string]float64),
+		jobsSize:            make(map[string]float64),
+		jobsCount:           make(map[string]float64),
+		jobsStatusCount:     make(map[string]float64),
+		mediaServers:        make(map[string]float64),
+		policyJobs:          make(map[string]float64),
+		policySuccesses:     make(map[string]float64),
+		jobsQueued:          make(map[string]float64),
+		imagesCount:         make(map[string]float64),
+		imagesBytes:         make(map[string]float64),
+		backedUpClients:     make(map[string]struct{}),
+		jobsElapsed:         make(map[string]float64),
+		jobsSubtypeCount:    make(map[string]float64),
+		jobsScheduleCount:   make(map[string]float64),
+		jobsFailedBytes:     make(map[string]float64),
+		jobsRetries:         make(map[string]float64),
+		jobsTransport:       make(map[string]float64),
+		policies:            make(map[string]struct{}),
+		backupBytes:         make(map[string]float64),
+		lastSuccessEnd:      make(map[string]time.Time),
+		policyLastSuccess:   make(map[string]float64),
+		backupSeconds:       make(map[string]float64),
+		diskPools:           make(map[string]float64),
+		auditEvents:         make(map[string]float64),
+		slpBacklogBytes:     make(map[string]float64),
+		slpIncompleteImages: make(map[string]float64),
+	}
+}
+
+// countPage records that one more API page was fetched from the endpoint.
+func (m *nbuMetrics) countPage(endpoint string) {
+	m.mu.Lock()
+	m.pagesFetched[endpoint]++
+	m.mu.Unlock()
+}
+
+// storageUnitNames are the unit names used in the help of the storage capacity metrics.
+var storageUnitNames = map[string]string{
+	models.StorageUnitBytes: "bytes",
+	models.StorageUnitMiB:   "MiB",
+	models.StorageUnitGiB:   "GiB",
+}
+
 // Define a struct for you collector that contains pointers
 // to prometheus descriptors for each metric you wish to expose.
 // Note you can also include fields of other types if they provide utility
 // but we just won't be exposing them as metrics.
 type NbuCollector struct {
 	cfg                models.Config
+	client             *nbuClient
+	clientMetrics      []prometheus.Collector
+	statusNames        statusNamer
+	mu                 sync.RWMutex
+	refreshMu          sync.Mutex
+	cached             *nbuMetrics
+	lastSuccess        time.Time
+	lastAttempt        time.Time
+	lastErr            error
+	stop               chan struct{}
+	done               chan struct{}
 	nbuDiskSize        *prometheus.Desc
+	nbuStorageUnits    *prometheus.Desc
+	nbuStorageWorm     *prometheus.Desc
 	nbuResponseTime    *prometheus.Desc
 	nbuJobsSize        *prometheus.Desc
 	nbuJobsCount       *prometheus.Desc
 	nbuJobsStatusCount *prometheus.Desc
+	nbuLastScrape      *prometheus.Desc
+	nbuMediaServerUp   *prometheus.Desc
+	nbuSuccessRatio    *prometheus.Desc
+	nbuPagesFetched    *prometheus.Desc
+	nbuUp              *prometheus.Desc
+	nbuCircuitOpen     *prometheus.Desc
+	nbuClockSkew       *prometheus.Desc
+	nbuJobsTruncated   *prometheus.Desc
+	nbuJobsQueued      *prometheus.Desc
+	nbuImagesCount     *prometheus.Desc
+	nbuImagesBytes     *prometheus.Desc
+	nbuBuildInfo       *prometheus.Desc
+	nbuClientsBackedUp *prometheus.Desc
+	nbuClientsKnown    *prometheus.Desc
+	nbuSLPBacklogBytes *prometheus.Desc
+	nbuSLPIncomplete   *prometheus.Desc
+	nbuScrapeInterval  *prometheus.Desc
+	nbuJobsElapsed     *prometheus.Desc
+	nbuOldestActiveJob *prometheus.Desc
+	nbuCatalogBackup   *prometheus.Desc
+	nbuDiskPoolSize    *prometheus.Desc
+	nbuJobsSubtype     *prometheus.Desc
+	nbuAPIVersion      *prometheus.Desc
+	nbuJobsFailedBytes *prometheus.Desc
+	nbuJobsRetries     *prometheus.Desc
+	nbuVMsProtected    *prometheus.Desc
+	nbuVMsUnprotected  *prometheus.Desc
+	nbuJobsThroughput  *prometheus.Desc
+	nbuPolicyLastOK    *prometheus.Desc
+	nbuAuditEvents     *prometheus.Desc
+	nbuJobsSchedule    *prometheus.Desc
+	nbuJobsTransport   *prometheus.Desc
+	nbuPolicyInfo      *prometheus.Desc
 }
 
 // NewNbuCollector You must create a constructor for you collector that
 // initializes every descriptor and returns a pointer to the collector
 func NewNbuCollector(cfg models.Config) *NbuCollector {
 
+	statusLabels := []string{"action", "status"}
+	if cfg.Server.StatusText {
+		statusLabels = append(statusLabels, "status_text")
+	}
+
+	// newDesc returns nil for the metrics listed in server.disabledMetrics,
+	// which are then neither described nor collected. Other metrics get server.constLabels.
+	newDesc := func(name, help string, labels []string) *prometheus.Desc {
+		if !cfg.MetricEnabled(name) {
+			return nil
+		}
+		return prometheus.NewDesc(name, help, labels, cfg.Server.ConstLabels)
+	}
+	// newJobTotalDesc describes a metric summed over the jobs. With nbuserver.incrementalJobs,
+	// it is a running total since the exporter started, so it is named as a counter.
+	newJobTotalDesc := func(name, help string, labels []string) *prometheus.Desc {
+		if cfg.NbuServer.IncrementalJobs && cfg.MetricEnabled(name) {
+			return prometheus.NewDesc(name+"_total", help, labels, cfg.Server.ConstLabels)
+		}
+		return newDesc(name, help, labels)
+	}
+
+	client := newNbuClient(cfg)
+	var clientMetrics []prometheus.Collector
+	for name, metric := range client.metrics() {
+		if cfg.MetricEnabled(name) {
+			clientMetrics = append(clientMetrics, metric)
+		}
+	}
+
 	return &NbuCollector{
-		cfg: cfg, // Injected configuration
-		nbuResponseTime: prometheus.NewDesc(
-			"nbu_response_time_ms",
+		cfg:           cfg, // Injected configuration
+		client:        client,
+		clientMetrics: clientMetrics,
+		statusNames:   newStatusNamer(cfg.Server.StatusNames),
+		nbuResponseTime: newDesc(
+			models.MetricResponseTimeMS,
 			"The server response time in millisecond",
-			nil, nil),
-		nbuDiskSize: prometheus.NewDesc(
-			"nbu_disk_bytes",
-			"The quantity of storage bytes",
-			[]string{"name", "type", "size"}, nil),
-		nbuJobsSize: prometheus.NewDesc(
-			"nbu_jobs_bytes",
+			nil),
+		nbuDiskSize: newDesc(
+			models.MetricDiskBytes,
+			fmt.Sprintf("The quantity of storage %s", storageUnitNames[cfg.GetStorageUnit()]),
+			[]string{"name", "type", "size"}),
+		nbuStorageUnits: newDesc(
+			models.MetricStorageUnitsCount,
+			"The quantity of storage units per storage type",
+			[]string{"storage_type"}),
+		nbuStorageWorm: newDesc(
+			models.MetricStorageWormEnabled,
+			"Whether the disk storage unit uses WORM (immutable) storage (1) or not (0)",
+			[]string{"name"}),
+		nbuJobsSize: newJobTotalDesc(
+			models.MetricJobsBytes,
 			"The quantity of processed bytes",
-			[]string{"action", "policy_type", "status"}, nil),
-		nbuJobsCount: prometheus.NewDesc(
-			"nbu_jobs_count",
+			[]string{"action", "policy_type", "status"}),
+		nbuJobsCount: newJobTotalDesc(
+			models.MetricJobsCount,
 			"The quantity of jobs",
-			[]string{"action", "policy_type", "status"}, nil),
-		nbuJobsStatusCount: prometheus.NewDesc(
-			"nbu_status_count",
+			[]string{"action", "policy_type", "status"}),
+		nbuJobsStatusCount: newJobTotalDesc(
+			models.MetricStatusCount,
 			"The quantity per status",
-			[]string{"action", "status"}, nil),
+			statusLabels),
+		nbuLastScrape: newDesc(
+			models.MetricLastScrapeTimestampSeconds,
+			"The Unix time of the last fully successful collection",
+			nil),
+		nbuMediaServerUp: newDesc(
+			models.MetricMediaServerUp,
+			"Whether the media server is active (1) or not (0)",
+			[]string{"name"}),
+		nbuSuccessRatio: newDesc(
+			models.MetricJobsSuccessRatio,
+			"The ratio of successful (status 0) jobs to all jobs per policy type",
+			[]string{"policy_type"}),
+		nbuPagesFetched: newDesc(
+			models.MetricAPIPagesFetched,
+			"The quantity of API pages fetched per endpoint during the last collection",
+			[]string{"endpoint"}),
+		nbuUp: newDesc(
+			models.MetricUp,
+			"Whether the NetBackup API answered during the last collection (1) or not (0)",
+			nil),
+		nbuCircuitOpen: newDesc(
+			models.MetricCircuitBreakerOpen,
+			"Whether requests to NetBackup are short-circuited after consecutive failures (1) or not (0)",
+			nil),
+		nbuClockSkew: newDesc(
+			models.MetricServerTimeSkewSeconds,
+			"The NetBackup server clock minus the exporter clock, from the Date header of the last response (1s resolution)",
+			nil),
+		nbuJobsTruncated: newDesc(
+			models.MetricJobsSeriesTruncated,
+			"The quantity of job series folded into the other series by nbuserver.maxJobSeries",
+			nil),
+		nbuJobsQueued: newDesc(
+			models.MetricJobsQueued,
+			"The quantity of queued jobs per queue reason",
+			[]string{"queue_reason"}),
+		nbuImagesCount: newDesc(
+			models.MetricCatalogImagesCount,
+			"The quantity of catalog images per policy type",
+			[]string{"policy_type"}),
+		nbuImagesBytes: newDesc(
+			models.MetricCatalogImagesBytes,
+			"The size of catalog images per policy type",
+			[]string{"policy_type"}),
+		nbuAPIVersion: newDesc(
+			models.MetricAPIVersionNumber,
+			"The NetBackup API version configured in nbuserver.apiVersion, as a number",
+			nil),
+		nbuBuildInfo: newDesc(
+			models.MetricExporterBuildInfo,
+			"A metric with a constant '1' value labeled by the exporter build information",
+			[]string{"version", "go_version", "commit"}),
+		nbuClientsBackedUp: newDesc(
+			models.MetricClientsBackedUp,
+			"The quantity of distinct clients with a successful backup job",
+			nil),
+		nbuClientsKnown: newDesc(
+			models.MetricRegisteredClients,
+			"The quantity of hosts registered with the primary server",
+			nil),
+		nbuDiskPoolSize: newDesc(
+			models.MetricDiskPoolBytes,
+			fmt.Sprintf("The usable, free and used %s of disk pools", storageUnitNames[cfg.GetStorageUnit()]),
+			[]string{"pool", "size"}),
+		nbuVMsProtected: newDesc(
+			models.MetricVMwareVMsProtected,
+			"The quantity of VMware virtual machines covered by a policy or protection plan",
+			nil),
+		nbuVMsUnprotected: newDesc(
+			models.MetricVMwareVMsUnprotected,
+			"The quantity of VMware virtual machines not covered by any policy or protection plan",
+			nil),
+		nbuAuditEvents: newDesc(
+			models.MetricAuditEventsCount,
+			"The quantity of audit events recorded within the scrapping interval per category",
+			[]string{"category"}),
+		nbuSLPBacklogBytes: newDesc(
+			models.MetricSLPBacklogBytes,
+			"The quantity of bytes waiting to be processed per storage lifecycle policy",
+			[]string{"slp_name"}),
+		nbuSLPIncomplete: newDesc(
+			models.MetricSLPIncompleteImages,
+			"The quantity of images not yet fully processed per storage lifecycle policy",
+			[]string{"slp_name"}),
+		nbuPolicyLastOK: newDesc(
+			models.MetricPolicyLastSuccessSeconds,
+			"The time in seconds since the latest successful job ended per policy type, among the jobs of the scrapping interval",
+			[]string{"policy_type"}),
+		nbuJobsThroughput: newDesc(
+			models.MetricJobsThroughputBytesPerSecond,
+			"The bytes transferred by backup jobs divided by their total elapsed time per policy type",
+			[]string{"policy_type"}),
+		nbuJobsFailedBytes: newJobTotalDesc(
+			models.MetricJobsFailedBytes,
+			"The quantity of bytes processed by failed jobs (status other than 0 and 1) per policy type",
+			[]string{"policy_type"}),
+		nbuJobsRetries: newJobTotalDesc(
+			models.MetricJobsRetriesCount,
+			"The quantity of job tries beyond the first one per policy type, for the jobs of the scrapping interval",
+			[]string{"policy_type"}),
+		nbuJobsSchedule: newJobTotalDesc(
+			models.MetricJobsScheduleCount,
+			"The quantity of jobs per schedule type and status",
+			[]string{"schedule_type", "status"}),
+		nbuJobsTransport: newJobTotalDesc(
+			models.MetricJobsTransportCount,
+			"The quantity of jobs per transport type, such as LAN, SAN or hotadd",
+			[]string{"transport_type"}),
+		nbuPolicyInfo: newDesc(
+			models.MetricPolicyInfo,
+			"A constant 1 for each policy name and type seen in the collected jobs",
+			[]string{"policy_name", "policy_type"}),
+		nbuJobsSubtype: newJobTotalDesc(
+			models.MetricJobsSubtypeCount,
+			"The quantity of jobs per job subtype",
+			[]string{"action", "subtype"}),
+		nbuJobsElapsed: newDesc(
+			models.MetricJobsElapsedSeconds,
+			"The longest job elapsed time per policy type",
+			[]string{"policy_type"}),
+		nbuOldestActiveJob: newDesc(
+			models.MetricOldestActiveJobSeconds,
+			"The time in seconds since the longest-running active job started, 0 if none is active",
+			nil),
+		nbuCatalogBackup: newDesc(
+			models.MetricCatalogBackupLastSuccessSeconds,
+			"The time in seconds since the last successful catalog backup ended, absent if none was found",
+			nil),
+		nbuScrapeInterval: newDesc(
+			models.MetricScrapeIntervalSeconds,
+			"The configured scrapping interval in seconds",
+			nil),
+	}
+}
+
+// CollectorMetricNames returns the sorted names of every metric the collector can emit.
+// The descriptors are built from the same models.KnownMetrics table used to validate
+// server.disabledMetrics, so that checks such as the consistency of the Grafana dashboard
+// stay in sync as metrics are added.
+func CollectorMetricNames() []string {
+	names := slices.Clone(models.KnownMetrics)
+	slices.Sort(names)
+	return names
+}
+
+// Start launches the background refresh of cached metrics when caching is enabled.
+// The cache is refreshed immediately, then every scrapping interval until Stop is called.
+func (collector *NbuCollector) Start() error {
+	if !collector.cfg.Server.CacheEnabled {
+		return nil
+	}
+	interval, err := collector.cfg.GetScrapingDuration()
+	if err != nil {
+		return err
+	}
+
+	collector.stop = make(chan struct{})
+	collector.done = make(chan struct{})
+	go collector.refreshLoop(interval)
+	return nil
+}
+
+// Stop terminates the background refresh and waits for it to exit.
+// It is a no-op when the refresh was never started.
+func (collector *NbuCollector) Stop() {
+	if collector.stop == nil {
+		return
+	}
+	close(collector.stop)
+	<-collector.done
+	collector.stop = nil
+}
+
+// refreshLoop periodically replaces the cached metrics until the stop channel is closed.
+func (collector *NbuCollector) refreshLoop(interval time.Duration) {
+	defer close(collector.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	collector.refresh()
+	for {
+		select {
+		case <-collector.stop:
+			return
+		case <-ticker.C:
+			collector.refresh()
+		}
+	}
+}
+
+// refresh fetches fresh metrics from NetBackup and stores them as the cached snapshot.
+// Concurrent refreshes, scheduled or requested through Refresh, run one at a time.
+func (collector *NbuCollector) refresh() error {
+	collector.refreshMu.Lock()
+	defer collector.refreshMu.Unlock()
+
+	metrics, err := collector.gather(context.Background())
+
+	collector.mu.Lock()
+	collector.cached = metrics
+	collector.mu.Unlock()
+	if err != nil {
+		logging.LogError(fmt.Sprintf("Cached metrics refreshed with errors: %v", err))
+		return err
+	}
+	logging.LogInfo("Cached metrics refreshed")
+	return nil
+}
+
+// Refresh replaces the cached metrics immediately, outside of the refresh schedule.
+// It fails with ErrCacheDisabled when caching is off, as every scrape then queries NetBackup.
+func (collector *NbuCollector) Refresh() error {
+	if !collector.cfg.Server.CacheEnabled {
+		return ErrCacheDisabled
+	}
+	return collector.refresh()
+}
+
+// lastSuccessTimestamp returns the Unix time of the last successful collection, or 0 if none.
+func (collector *NbuCollector) lastSuccessTimestamp() float64 {
+	collector.mu.RLock()
+	defer collector.mu.RUnlock()
+	if collector.lastSuccess.IsZero() {
+		return 0
+	}
+	return float64(collector.lastSuccess.UnixNano()) / float64(time.Second)
+}
+
+// snapshot returns the last cached metrics, or nil when no refresh has completed yet.
+func (collector *NbuCollector) snapshot() *nbuMetrics {
+	collector.mu.RLock()
+	defer collector.mu.RUnlock()
+	return collector.cached
+}
+
+// gather fetches information from NetBackup for every enabled collector.
+// The fetches run concurrently, each filling its own maps, so a slow or failing
+// endpoint neither delays nor aborts the others.
+// The last success timestamp is only advanced when every fetch succeeded.
+// The time and outcome of each collection are kept for the health endpoint.
+// Requests still running when ctx is done are aborted.
+func (collector *NbuCollector) gather(ctx context.Context) (*nbuMetrics, error) {
+	start := time.Now()
+	metrics := newNbuMetrics()
+	var fetches []func() error
+	if collector.cfg.CollectorEnabled(models.CollectorStorage) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchStorage(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorJobs) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchAllJobs(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorMediaServers) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchMediaServers(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorImages) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchImages(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorDiskPools) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchDiskPools(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorVMware) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchVMwareProtection(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorAudit) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchAuditEvents(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorSLP) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchSLPStatus(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorClients) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchClients(ctx, metrics)
+		})
+	}
+	if collector.cfg.CollectorEnabled(models.CollectorCatalog) {
+		fetches = append(fetches, func() error {
+			return collector.client.fetchCatalogBackup(ctx, metrics)
+		})
+	}
+
+	errs := make([]error, len(fetches))
+	var wg sync.WaitGroup
+	for i, fetch := range fetches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fetch()
+		}()
+	}
+	wg.Wait()
+	metrics.up = collector.client.respondedSince(start)
+	err := errors.Join(errs...)
+	collector.checkClockSkew()
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.lastAttempt = time.Now()
+	collector.lastErr = err
+	if err != nil {
+		return metrics, err
+	}
+	collector.lastSuccess = collector.lastAttempt
+	return metrics, nil
+}
+
+// checkClockSkew logs a warning when the NetBackup clock differs from the local clock by more
+// than nbuserver.maxClockSkew, as the lookback filters of the jobs, images and audit events
+// would then miss or count twice some of them.
+func (collector *NbuCollector) checkClockSkew() {
+	skew, ok := collector.client.serverClockSkew()
+	if ok && skew.Abs() > collector.cfg.GetMaxClockSkew() {
+		logging.LogWarning(fmt.Sprintf("NetBackup server clock is %s off the exporter clock, check the time synchronization", skew.Round(time.Second)))
 	}
 }
 
@@ -55,47 +584,263 @@ func NewNbuCollector(cfg models.Config) *NbuCollector {
 func (collector *NbuCollector) Describe(ch chan<- *prometheus.Desc) {
 
 	//Update this section with the each metric you create for a given collector
-	ch <- collector.nbuDiskSize
-	ch <- collector.nbuResponseTime
-	ch <- collector.nbuJobsSize
-	ch <- collector.nbuJobsCount
-	ch <- collector.nbuJobsStatusCount
+	for _, desc := range []*prometheus.Desc{
+		collector.nbuDiskSize,
+		collector.nbuStorageUnits,
+		collector.nbuStorageWorm,
+		collector.nbuResponseTime,
+		collector.nbuJobsSize,
+		collector.nbuJobsCount,
+		collector.nbuJobsStatusCount,
+		collector.nbuLastScrape,
+		collector.nbuMediaServerUp,
+		collector.nbuSuccessRatio,
+		collector.nbuPagesFetched,
+		collector.nbuUp,
+		collector.nbuCircuitOpen,
+		collector.nbuClockSkew,
+		collector.nbuJobsTruncated,
+		collector.nbuJobsQueued,
+		collector.nbuImagesCount,
+		collector.nbuImagesBytes,
+		collector.nbuBuildInfo,
+		collector.nbuAPIVersion,
+		collector.nbuClientsBackedUp,
+		collector.nbuClientsKnown,
+		collector.nbuDiskPoolSize,
+		collector.nbuVMsProtected,
+		collector.nbuVMsUnprotected,
+		collector.nbuAuditEvents,
+		collector.nbuSLPBacklogBytes,
+		collector.nbuSLPIncomplete,
+		collector.nbuScrapeInterval,
+		collector.nbuJobsElapsed,
+		collector.nbuJobsSubtype,
+		collector.nbuJobsSchedule,
+		collector.nbuJobsTransport,
+		collector.nbuPolicyInfo,
+		collector.nbuJobsFailedBytes,
+		collector.nbuJobsRetries,
+		collector.nbuJobsThroughput,
+		collector.nbuPolicyLastOK,
+		collector.nbuOldestActiveJob,
+		collector.nbuCatalogBackup,
+	} {
+		// Disabled metrics have no descriptor.
+		if desc != nil {
+			ch <- desc
+		}
+	}
+	for _, metric := range collector.clientMetrics {
+		metric.Describe(ch)
+	}
 
 }
 
-// Collect implements required collect function for all promehteus collectors
+// Collect implements required collect function for all promehteus collectors.
+// With caching enabled, the last snapshot is served instead of querying NetBackup.
 func (collector *NbuCollector) Collect(ch chan<- prometheus.Metric) {
+	collector.collect(context.Background(), ch)
+}
+
+// collect sends the metrics to the channel, querying NetBackup within ctx unless caching is enabled.
+func (collector *NbuCollector) collect(ctx context.Context, ch chan<- prometheus.Metric) {
+
+	var metrics *nbuMetrics
+	if collector.cfg.Server.CacheEnabled {
+		metrics = collector.snapshot()
+	} else {
+		var err error
+		metrics, err = collector.gather(ctx)
+		switch {
+		case errors.Is(err, ErrPartialResults):
+			logging.LogWarning(fmt.Sprintf("Collection incomplete, reporting partial results: %v", err))
+		case err != nil:
+			logging.LogError(fmt.Sprintf("Collection failed: %v", err))
+		}
+	}
 
-	//Implement logic here to determine proper metric value to return to prometheus
-	//for each descriptor or call other functions that do so.
+	collector.send(ch, collector.nbuBuildInfo, prometheus.GaugeValue, 1, version.Version, version.GoVersion(), version.Commit)
+	if apiVersion, err := strconv.ParseFloat(collector.cfg.NbuServer.APIVersion, 64); err == nil {
+		collector.send(ch, collector.nbuAPIVersion, prometheus.GaugeValue, apiVersion)
+	}
 
-	var disks = make(map[string]float64)
-	fetchStorage(disks, collector.cfg)
-	var jobsSize = make(map[string]float64)
-	var jobsCount = make(map[string]float64)
-	var jobsStatusCount = make(map[string]float64)
-	fetchAllJobs(jobsSize, jobsCount, jobsStatusCount, collector.cfg)
+	// The timestamp is emitted even after a failed collection so the gap is visible.
+	collector.send(ch, collector.nbuLastScrape, prometheus.GaugeValue, collector.lastSuccessTimestamp())
+	up := 0.0
+	if metrics != nil && metrics.up {
+		up = 1
+	}
+	collector.send(ch, collector.nbuUp, prometheus.GaugeValue, up)
+	circuitOpen := 0.0
+	if collector.client.breaker.isOpen() {
+		circuitOpen = 1
+	}
+	collector.send(ch, collector.nbuCircuitOpen, prometheus.GaugeValue, circuitOpen)
+	if skew, ok := collector.client.serverClockSkew(); ok {
+		collector.send(ch, collector.nbuClockSkew, prometheus.GaugeValue, skew.Seconds())
+	}
+	if interval, err := collector.cfg.GetScrapingDuration(); err == nil {
+		collector.send(ch, collector.nbuScrapeInterval, prometheus.GaugeValue, interval.Seconds())
+	}
+	for _, metric := range collector.clientMetrics {
+		metric.Collect(ch)
+	}
+	if metrics == nil {
+		return
+	}
 
 	//Write latest value for each metric in the prometheus metric channel.
 	//Note that you can pass CounterValue, GaugeValue, or UntypedValue types here
-	for key, value := range disks {
+	for key, value := range metrics.disks {
+		labels := strings.Split(key, "|")
+		collector.send(ch, collector.nbuDiskSize, prometheus.GaugeValue, value/collector.cfg.StorageUnitSize(), labels[0], labels[1], labels[2])
+	}
+
+	for storageType, value := range metrics.storageUnits {
+		collector.send(ch, collector.nbuStorageUnits, prometheus.GaugeValue, value, storageType)
+	}
+
+	for name, value := range metrics.storageWorm {
+		collector.send(ch, collector.nbuStorageWorm, prometheus.GaugeValue, value, name)
+	}
+
+	// With nbuserver.incrementalJobs, the summed job metrics are running totals, exposed as counters.
+	jobTotalsType := prometheus.GaugeValue
+	if collector.cfg.NbuServer.IncrementalJobs {
+		jobTotalsType = prometheus.CounterValue
+	}
+
+	for key, value := range metrics.jobsSize {
+		labels := strings.Split(key, "|")
+		collector.send(ch, collector.nbuJobsSize, jobTotalsType, value, labels[0], labels[1], labels[2])
+	}
+
+	for key, value := range metrics.jobsCount {
+		labels := strings.Split(key, "|")
+		collector.send(ch, collector.nbuJobsCount, jobTotalsType, value, labels[0], labels[1], labels[2])
+	}
+
+	for key, value := range metrics.jobsStatusCount {
+		labels := strings.Split(key, "|")
+		if collector.cfg.Server.StatusText {
+			labels = append(labels, collector.statusNames.name(labels[1]))
+		}
+		collector.send(ch, collector.nbuJobsStatusCount, jobTotalsType, value, labels...)
+	}
+
+	for policyType, total := range metrics.policyJobs {
+		if total == 0 {
+			continue
+		}
+		collector.send(ch, collector.nbuSuccessRatio, prometheus.GaugeValue, metrics.policySuccesses[policyType]/total, policyType)
+	}
+
+	if collector.cfg.CollectorEnabled(models.CollectorJobs) {
+		collector.send(ch, collector.nbuClientsBackedUp, prometheus.GaugeValue, float64(len(metrics.backedUpClients)))
+		collector.send(ch, collector.nbuOldestActiveJob, prometheus.GaugeValue, metrics.oldestActiveJob)
+	}
+
+	for policyType, value := range metrics.policyLastSuccess {
+		collector.send(ch, collector.nbuPolicyLastOK, prometheus.GaugeValue, value, policyType)
+	}
+
+	for policyType, seconds := range metrics.backupSeconds {
+		if seconds == 0 {
+			continue
+		}
+		collector.send(ch, collector.nbuJobsThroughput, prometheus.GaugeValue, metrics.backupBytes[policyType]/seconds, policyType)
+	}
+
+	for policyType, value := range metrics.jobsFailedBytes {
+		collector.send(ch, collector.nbuJobsFailedBytes, jobTotalsType, value, policyType)
+	}
+
+	for policyType, value := range metrics.jobsRetries {
+		collector.send(ch, collector.nbuJobsRetries, jobTotalsType, value, policyType)
+	}
+
+	for key, value := range metrics.jobsScheduleCount {
 		labels := strings.Split(key, "|")
-		ch <- prometheus.MustNewConstMetric(collector.nbuDiskSize, prometheus.GaugeValue, value, labels[0], labels[1], labels[2])
+		collector.send(ch, collector.nbuJobsSchedule, jobTotalsType, value, labels[0], labels[1])
 	}
 
-	for key, value := range jobsSize {
+	for transportType, value := range metrics.jobsTransport {
+		collector.send(ch, collector.nbuJobsTransport, jobTotalsType, value, transportType)
+	}
+
+	for key := range metrics.policies {
 		labels := strings.Split(key, "|")
-		ch <- prometheus.MustNewConstMetric(collector.nbuJobsSize, prometheus.GaugeValue, value, labels[0], labels[1], labels[2])
+		collector.send(ch, collector.nbuPolicyInfo, prometheus.GaugeValue, 1, labels[0], labels[1])
 	}
 
-	for key, value := range jobsCount {
+	for key, value := range metrics.jobsSubtypeCount {
 		labels := strings.Split(key, "|")
-		ch <- prometheus.MustNewConstMetric(collector.nbuJobsCount, prometheus.GaugeValue, value, labels[0], labels[1], labels[2])
+		collector.send(ch, collector.nbuJobsSubtype, jobTotalsType, value, labels[0], labels[1])
+	}
+
+	for policyType, value := range metrics.jobsElapsed {
+		collector.send(ch, collector.nbuJobsElapsed, prometheus.GaugeValue, value, policyType)
+	}
+
+	for reason, value := range metrics.jobsQueued {
+		collector.send(ch, collector.nbuJobsQueued, prometheus.GaugeValue, value, reason)
+	}
+
+	for name, value := range metrics.mediaServers {
+		collector.send(ch, collector.nbuMediaServerUp, prometheus.GaugeValue, value, name)
+	}
+
+	collector.send(ch, collector.nbuJobsTruncated, prometheus.GaugeValue, metrics.jobsSeriesTruncated)
+
+	for policyType, value := range metrics.imagesCount {
+		collector.send(ch, collector.nbuImagesCount, prometheus.GaugeValue, value, policyType)
+	}
+
+	for policyType, value := range metrics.imagesBytes {
+		collector.send(ch, collector.nbuImagesBytes, prometheus.GaugeValue, value, policyType)
 	}
 
-	for key, value := range jobsStatusCount {
+	for key, value := range metrics.diskPools {
 		labels := strings.Split(key, "|")
-		ch <- prometheus.MustNewConstMetric(collector.nbuJobsStatusCount, prometheus.GaugeValue, value, labels[0], labels[1])
+		collector.send(ch, collector.nbuDiskPoolSize, prometheus.GaugeValue, value/collector.cfg.StorageUnitSize(), labels[0], labels[1])
 	}
 
+	if collector.cfg.CollectorEnabled(models.CollectorClients) {
+		collector.send(ch, collector.nbuClientsKnown, prometheus.GaugeValue, metrics.registeredClients)
+	}
+
+	if metrics.catalogBackupFound {
+		collector.send(ch, collector.nbuCatalogBackup, prometheus.GaugeValue, metrics.catalogBackupAge)
+	}
+
+	if collector.cfg.CollectorEnabled(models.CollectorVMware) {
+		collector.send(ch, collector.nbuVMsProtected, prometheus.GaugeValue, metrics.vmsProtected)
+		collector.send(ch, collector.nbuVMsUnprotected, prometheus.GaugeValue, metrics.vmsUnprotected)
+	}
+
+	for category, value := range metrics.auditEvents {
+		collector.send(ch, collector.nbuAuditEvents, prometheus.GaugeValue, value, category)
+	}
+
+	for name, value := range metrics.slpBacklogBytes {
+		collector.send(ch, collector.nbuSLPBacklogBytes, prometheus.GaugeValue, value, name)
+	}
+
+	for name, value := range metrics.slpIncompleteImages {
+		collector.send(ch, collector.nbuSLPIncomplete, prometheus.GaugeValue, value, name)
+	}
+
+	for endpoint, value := range metrics.pagesFetched {
+		collector.send(ch, collector.nbuPagesFetched, prometheus.GaugeValue, value, endpoint)
+	}
+
+}
+
+// send writes a constant metric to the channel, unless the metric is disabled.
+func (collector *NbuCollector) send(ch chan<- prometheus.Metric, desc *prometheus.Desc, valueType prometheus.ValueType, value float64, labels ...string) {
+	if desc == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, valueType, value, labels...)
 }
@@ -0,0 +1,422 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// gatherValues collects the registry and returns the value of the first series of each metric.
+func gatherValues(t *testing.T, registry *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.Gauge != nil:
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		case metric.Counter != nil:
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		}
+	}
+	return values
+}
+
+// gatherSeries collects the registry and returns the gauge values of the named metric by first label value.
+func gatherSeries(t *testing.T, registry *prometheus.Registry, name string) map[string]float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	series := make(map[string]float64)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			series[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+	return series
+}
+
+func TestCacheServesSnapshotBetweenRefreshes(t *testing.T) {
+	var storageRequests atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/storage-units" {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		// Each request reports one more disk storage unit.
+		units := storageRequests.Add(1)
+		writeJSON(w, fmt.Sprintf(`{"data":[{"attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":%d}}]}`, units))
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.CacheEnabled = true
+	collector := NewNbuCollector(cfg)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	if got, ok := gatherValues(t, registry)["nbu_disk_bytes"]; ok {
+		t.Errorf("nbu_disk_bytes before the first refresh = %v, want none", got)
+	}
+	if err := collector.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); collector.snapshot() == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("the cache was not refreshed at start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for range 3 {
+		if got := gatherValues(t, registry)["nbu_disk_bytes"]; got != 1 {
+			t.Errorf("nbu_disk_bytes = %v, want the cached value 1", got)
+		}
+	}
+	if got := storageRequests.Load(); got != 1 {
+		t.Errorf("storage requests = %d, want only the refresh at start", got)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		collector.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return")
+	}
+	select {
+	case <-collector.done:
+	default:
+		t.Error("the refresh goroutine is still running after Stop()")
+	}
+	collector.Stop()
+}
+
+func TestGatherFetchesConcurrently(t *testing.T) {
+	const delay = 300 * time.Millisecond
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !isStateQuery(r) {
+			time.Sleep(delay)
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	collector := NewNbuCollector(testConfig(t, server))
+
+	start := time.Now()
+	if _, err := collector.gather(context.Background()); err != nil {
+		t.Fatalf("gather() error = %v", err)
+	}
+	// Storage and jobs each wait for the delay: sequential fetches would take twice as long.
+	if elapsed := time.Since(start); elapsed >= delay*3/2 {
+		t.Errorf("gather() took %v, want close to %v", elapsed, delay)
+	}
+}
+
+// scrape scrapes the handler with the Accept header and returns the exposed sample names and types.
+func scrape(t *testing.T, handler http.Handler, accept string) (names map[string]bool, types []string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	names = make(map[string]bool)
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# TYPE "):
+			types = append(types, strings.TrimPrefix(line, "# TYPE "))
+		case line != "" && !strings.HasPrefix(line, "#"):
+			names[strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]] = true
+		}
+	}
+	return names, types
+}
+
+func TestCollectReportsPartialResults(t *testing.T) {
+	const last, failing = 4, 2
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if isStateQuery(r) {
+			writeJSON(w, `{"data":[]}`)
+			return
+		}
+		if r.URL.Query().Get(queryParamOffset) == strconv.Itoa(failing) {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		writeJobPage(w, r, last)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorJobs}
+	collector := NewNbuCollector(cfg)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
+
+	values := gatherValues(t, registry)
+	if got := values["nbu_jobs_count"]; got != failing {
+		t.Errorf("nbu_jobs_count = %v, want the %d jobs of the pages before the failure", got, failing)
+	}
+	if got := values["nbu_up"]; got != 1 {
+		t.Errorf("nbu_up = %v, want 1 as NetBackup answered", got)
+	}
+	if got := values["nbu_last_scrape_timestamp_seconds"]; got != 0 {
+		t.Errorf("nbu_last_scrape_timestamp_seconds = %v, want 0 as the collection was incomplete", got)
+	}
+	if health := collector.health(); health.LastScrape == nil || health.LastScrapeSuccess {
+		t.Errorf("health = %+v, want a failed last scrape", health)
+	}
+}
+
+func TestMetricNamesDoNotDependOnFormat(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/storage/storage-units":
+			writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"Disk","storageServerType":"MSDP","freeCapacityBytes":1}}]}`)
+		case "/admin/jobs":
+			writeJSON(w, `{"data":[{"attributes":{"jobType":"BACKUP","policyType":"STANDARD","status":0,"kilobytesTransferred":1}}]}`)
+		default:
+			writeJSON(w, `{"data":[]}`)
+		}
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(testConfig(t, server)))
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+
+	text, _ := scrape(t, handler, "text/plain")
+	openMetrics, types := scrape(t, handler, "application/openmetrics-text; version=1.0.0")
+	if len(text) == 0 || !maps.Equal(text, openMetrics) {
+		t.Errorf("text format names = %v, OpenMetrics names = %v, want the same", slices.Sorted(maps.Keys(text)), slices.Sorted(maps.Keys(openMetrics)))
+	}
+	// OpenMetrics exposes a counter whose name lacks the _total suffix as unknown.
+	for _, metricType := range types {
+		if strings.HasSuffix(metricType, " unknown") {
+			t.Errorf("OpenMetrics type %q, want a counter named with _total or a gauge", metricType)
+		}
+	}
+}
+
+func TestCollectReportsUp(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == defaultStoragePath {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(testConfig(t, server)))
+	if got := gatherValues(t, registry)["nbu_up"]; got != 1 {
+		t.Errorf("nbu_up with a failing endpoint = %v, want 1", got)
+	}
+
+	unreachable := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+	cfg := testConfig(t, unreachable)
+	unreachable.Close()
+	registry = prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+	values := gatherValues(t, registry)
+	if got, ok := values["nbu_up"]; !ok || got != 0 {
+		t.Errorf("nbu_up with an unreachable server = %v (exposed %t), want 0", got, ok)
+	}
+}
+
+func TestCollectObservesRequestDurations(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == defaultJobsPath && !isStateQuery(r) {
+			writeJobPage(w, r, 1)
+			return
+		}
+		writeJSON(w, `{"data":[]}`)
+	})
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(testConfig(t, server)))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		if family.GetName() != "nbu_api_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			histogram := metric.GetHistogram()
+			if len(histogram.GetBucket()) != len(prometheus.DefBuckets) {
+				t.Errorf("%d buckets, want %d", len(histogram.GetBucket()), len(prometheus.DefBuckets))
+			}
+			counts[metric.GetLabel()[0].GetValue()] = histogram.GetSampleCount()
+		}
+	}
+	// Two pages of jobs in the lookback window and one page each of active and queued jobs.
+	if want := map[string]uint64{"jobs": 4, "storage": 1}; !maps.Equal(counts, want) {
+		t.Errorf("observed requests = %v, want %v", counts, want)
+	}
+}
+
+func TestDisabledMetricsAreNotExposed(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":1}}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+	values := gatherValues(t, registry)
+	for _, name := range []string{"nbu_disk_bytes", "nbu_api_rate_limited_total"} {
+		if _, ok := values[name]; !ok {
+			t.Fatalf("%s missing while enabled (all: %v)", name, values)
+		}
+	}
+
+	cfg.Server.DisabledMetrics = []string{"nbu_disk_bytes", "nbu_api_rate_limited_total"}
+	registry = prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+	values = gatherValues(t, registry)
+	for _, name := range cfg.Server.DisabledMetrics {
+		if _, ok := values[name]; ok {
+			t.Errorf("%s exposed while disabled", name)
+		}
+	}
+	if _, ok := values["nbu_storage_units_count"]; !ok {
+		t.Error("nbu_storage_units_count missing, want only the disabled metrics dropped")
+	}
+}
+
+func TestConstLabelsAppearOnAllMetrics(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":1}}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	cfg.Server.ConstLabels = map[string]string{"env": "prod"}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			found := false
+			for _, label := range metric.GetLabel() {
+				found = found || label.GetName() == "env" && label.GetValue() == "prod"
+			}
+			if !found {
+				t.Errorf("%s%v has no env=\"prod\" label", family.GetName(), metric.GetLabel())
+			}
+		}
+	}
+
+	cfg.Server.ConstLabels = map[string]string{"name": "prod"}
+	if err := prometheus.NewPedanticRegistry().Register(NewNbuCollector(cfg)); err == nil {
+		t.Error("Register() with a constant label colliding with a metric label succeeded, want an error")
+	}
+}
+
+func TestServerTimeSkewFromDateHeader(t *testing.T) {
+	const offset = 2 * time.Hour
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(offset).UTC().Format(http.TimeFormat))
+		writeJSON(w, `{"data":[]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewNbuCollector(cfg))
+
+	// The Date header has a one second resolution.
+	if got := gatherValues(t, registry)["nbu_server_time_skew_seconds"]; got < offset.Seconds()-2 || got > offset.Seconds()+2 {
+		t.Errorf("nbu_server_time_skew_seconds = %v, want about %v", got, offset.Seconds())
+	}
+}
+
+// describeCount returns the number of descriptors sent by Describe.
+func describeCount(collector prometheus.Collector) int {
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(ch)
+		close(ch)
+	}()
+	count := 0
+	for range ch {
+		count++
+	}
+	return count
+}
+
+func TestCollectorMetricNamesMatchDescribe(t *testing.T) {
+	names := CollectorMetricNames()
+	if !slices.IsSorted(names) {
+		t.Errorf("CollectorMetricNames() = %v, want sorted", names)
+	}
+	if got := describeCount(NewNbuCollector(models.Config{})); got != len(names) {
+		t.Errorf("Describe() sent %d descriptors, want one per name of CollectorMetricNames() (%d)", got, len(names))
+	}
+
+	// Each name gates exactly one descriptor, so the names and descriptors match one to one.
+	for _, name := range names {
+		var cfg models.Config
+		cfg.Server.DisabledMetrics = []string{name}
+		if got := describeCount(NewNbuCollector(cfg)); got != len(names)-1 {
+			t.Errorf("Describe() with %s disabled sent %d descriptors, want %d", name, got, len(names)-1)
+		}
+	}
+}
+
+func TestMetricsConsistencyGrafanaDashboard(t *testing.T) {
+	files, err := filepath.Glob("../../grafana/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files = append(files, "../../README.md")
+
+	known := CollectorMetricNames()
+	metricName := regexp.MustCompile(`nbu_[a-z_]+`)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range metricName.FindAllString(string(content), -1) {
+			if name == "nbu_exporter" {
+				continue
+			}
+			// Histograms and counters are also referenced by their series suffixes.
+			base := name
+			for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+				if trimmed, ok := strings.CutSuffix(name, suffix); ok && slices.Contains(known, trimmed) {
+					base = trimmed
+				}
+			}
+			if !slices.Contains(known, base) {
+				t.Errorf("%s references %s, which the collector does not emit", filepath.Base(file), name)
+			}
+		}
+	}
+}
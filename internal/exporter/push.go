@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// lineProtocolContentType is the media type of the InfluxDB line protocol.
+const lineProtocolContentType = "text/plain; charset=utf-8"
+
+// Escapers of the InfluxDB line protocol for measurements, and for tag keys and values.
+var (
+	measurementEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// Pusher periodically collects the metrics and sends them to an endpoint accepting the
+// InfluxDB line protocol, such as the /write API of InfluxDB.
+type Pusher struct {
+	collector *NbuCollector
+	endpoint  string
+	client    *http.Client
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewPusher creates a pusher sending the collector metrics to server.pushEndpoint.
+func NewPusher(collector *NbuCollector) *Pusher {
+	return &Pusher{
+		collector: collector,
+		endpoint:  collector.cfg.Server.PushEndpoint,
+		client:    &http.Client{Timeout: timeout},
+	}
+}
+
+// Start pushes the metrics immediately, then every scrapping interval until Stop is called.
+func (p *Pusher) Start() error {
+	interval, err := p.collector.cfg.GetScrapingDuration()
+	if err != nil {
+		return err
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.pushLoop(interval)
+	return nil
+}
+
+// Stop terminates the periodic push and waits for it to exit.
+// It is a no-op when the push was never started.
+func (p *Pusher) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+}
+
+// pushLoop pushes the metrics every interval until the stop channel is closed.
+// Each collection is bounded by the interval so that pushes do not pile up.
+func (p *Pusher) pushLoop(interval time.Duration) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := p.Push(ctx); err != nil {
+			logging.LogError(fmt.Sprintf("Metrics push failed: %v", err))
+		}
+		cancel()
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push collects the metrics within ctx and posts them as line protocol to the endpoint.
+func (p *Pusher) Push(ctx context.Context) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(contextCollector{NbuCollector: p.collector, ctx: ctx}); err != nil {
+		return err
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	var body bytes.Buffer
+	writeLineProtocol(&body, families, time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerContentType, lineProtocolContentType)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request to %s failed: %w", p.endpoint, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", p.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// writeLineProtocol writes one line per metric, with the metric name as measurement
+// and the labels as tags, all stamped with the given time.
+// Gauges, counters and untyped metrics have a single "value" field; histograms and
+// summaries have "count" and "sum" fields. Non-finite values are skipped, as the line
+// protocol cannot represent them.
+func writeLineProtocol(w io.Writer, families []*dto.MetricFamily, timestamp time.Time) {
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			fields := lineProtocolFields(metric)
+			if len(fields) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s%s %s %d\n", measurementEscaper.Replace(family.GetName()), lineProtocolTags(metric.GetLabel()), strings.Join(fields, ","), timestamp.UnixNano())
+		}
+	}
+}
+
+// lineProtocolTags returns the labels as a tag set, sorted by name as the line protocol
+// recommends. Empty label values are left out, as tags cannot be empty.
+func lineProtocolTags(labels []*dto.LabelPair) string {
+	var tags []string
+	for _, label := range labels {
+		if label.GetValue() == "" {
+			continue
+		}
+		tags = append(tags, tagEscaper.Replace(label.GetName())+"="+tagEscaper.Replace(label.GetValue()))
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.Strings(tags)
+	return "," + strings.Join(tags, ",")
+}
+
+// lineProtocolFields returns the fields of a metric, formatted as name=value.
+func lineProtocolFields(metric *dto.Metric) []string {
+	values := map[string]float64{}
+	switch {
+	case metric.Gauge != nil:
+		values["value"] = metric.GetGauge().GetValue()
+	case metric.Counter != nil:
+		values["value"] = metric.GetCounter().GetValue()
+	case metric.Untyped != nil:
+		values["value"] = metric.GetUntyped().GetValue()
+	case metric.Histogram != nil:
+		values["count"] = float64(metric.GetHistogram().GetSampleCount())
+		values["sum"] = metric.GetHistogram().GetSampleSum()
+	case metric.Summary != nil:
+		values["count"] = float64(metric.GetSummary().GetSampleCount())
+		values["sum"] = metric.GetSummary().GetSampleSum()
+	}
+
+	var fields []string
+	for name, value := range values {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		fields = append(fields, name+"="+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	sort.Strings(fields)
+	return fields
+}
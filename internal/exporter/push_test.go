@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWriteLineProtocol(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	disk := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "nbu_disk_bytes"}, []string{"name", "size", "type"})
+	duration := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "nbu_api_request_duration_seconds"})
+	registry.MustRegister(disk, duration)
+	disk.WithLabelValues("disk pool,1", "free", "").Set(1.5)
+	disk.WithLabelValues("disk", "used", "").Set(math.NaN())
+	duration.Observe(0.25)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body strings.Builder
+	writeLineProtocol(&body, families, time.Unix(1, 0))
+	want := `nbu_api_request_duration_seconds count=1,sum=0.25 1000000000
+nbu_disk_bytes,name=disk\ pool\,1,size=free value=1.5 1000000000
+`
+	if body.String() != want {
+		t.Errorf("writeLineProtocol() =\n%s\nwant\n%s", body.String(), want)
+	}
+}
+
+func TestPushSendsLineProtocol(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","storageServerType":"PureDisk","freeCapacityBytes":1,"usedCapacityBytes":2}}]}`)
+	})
+	type push struct{ contentType, body string }
+	pushes := make(chan push, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		pushes <- push{contentType: r.Header.Get(headerContentType), body: string(body)}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	cfg.Server.PushEndpoint = receiver.URL
+	if err := NewPusher(NewNbuCollector(cfg)).Push(context.Background()); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	received := <-pushes
+	if received.contentType != lineProtocolContentType {
+		t.Errorf("Content-Type = %q, want %q", received.contentType, lineProtocolContentType)
+	}
+	var found bool
+	for _, line := range strings.Split(strings.TrimSuffix(received.body, "\n"), "\n") {
+		// measurement[,tags] fields timestamp
+		if parts := strings.Split(line, " "); len(parts) != 3 || !strings.Contains(parts[1], "=") {
+			t.Errorf("line %q is not measurement[,tags] fields timestamp", line)
+		}
+		if strings.HasPrefix(line, "nbu_disk_bytes,") && strings.Contains(line, "size=free") {
+			found = true
+			if !strings.Contains(line, " value=1 ") {
+				t.Errorf("line %q, want value=1", line)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("pushed body has no free nbu_disk_bytes line:\n%s", received.body)
+	}
+}
+
+func TestPushReportsRejectedWrites(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, `{"data":[]}`)
+	})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	t.Cleanup(receiver.Close)
+
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+	cfg.Server.PushEndpoint = receiver.URL
+	err := NewPusher(NewNbuCollector(cfg)).Push(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Push() error = %v, want the rejected status", err)
+	}
+}
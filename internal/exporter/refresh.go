@@ -0,0 +1,29 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// RefreshHandler returns a handler that refreshes the cached metrics on POST requests.
+// It answers 200 once the refresh succeeded, 502 when NetBackup could not be fully
+// collected and 409 when caching is disabled.
+func (collector *NbuCollector) RefreshHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		err := collector.Refresh()
+		switch {
+		case errors.Is(err, ErrCacheDisabled):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, fmt.Sprintf("refresh failed: %v", err), http.StatusBadGateway)
+		default:
+			fmt.Fprintln(w, "refreshed")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	var fail atomic.Bool
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, `{"data":[{"attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":1}}]}`)
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.CacheEnabled = true
+	collector := NewNbuCollector(cfg)
+	handler := collector.RefreshHandler()
+
+	post := func() int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+		return recorder.Code
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/refresh", nil))
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET /refresh = %d, Allow %q, want 405 allowing POST", recorder.Code, recorder.Header().Get("Allow"))
+	}
+
+	if code := post(); code != http.StatusOK {
+		t.Errorf("POST /refresh = %d, want 200", code)
+	}
+	if collector.snapshot() == nil {
+		t.Error("no cached metrics after a successful refresh")
+	}
+
+	fail.Store(true)
+	if code := post(); code != http.StatusBadGateway {
+		t.Errorf("POST /refresh with NetBackup failing = %d, want 502", code)
+	}
+
+	cfg.Server.CacheEnabled = false
+	handler = NewNbuCollector(cfg).RefreshHandler()
+	if code := post(); code != http.StatusConflict {
+		t.Errorf("POST /refresh without caching = %d, want 409", code)
+	}
+}
@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// headerScrapeTimeout is sent by Prometheus with the scrape timeout of the target.
+	headerScrapeTimeout = "X-Prometheus-Scrape-Timeout-Seconds"
+	// scrapeTimeoutOffset is kept from the scrape timeout to write the response in time.
+	scrapeTimeoutOffset = 500 * time.Millisecond
+)
+
+// contextCollector collects the NbuCollector metrics within a request context.
+type contextCollector struct {
+	*NbuCollector
+	ctx context.Context
+}
+
+// Collect sends the metrics, aborting the NetBackup requests when the context is done.
+func (c contextCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collect(c.ctx, ch)
+}
+
+// Handler serves the metrics of the default registry together with the collector metrics.
+// The collector must not be registered in the default registry: it is registered for each
+// request, so that the collection is bounded by the X-Prometheus-Scrape-Timeout-Seconds
+// header, minus a short margin to send the response.
+func (collector *NbuCollector) Handler(opts promhttp.HandlerOpts) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timeout, ok := scrapeTimeout(r.Header.Get(headerScrapeTimeout)); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(contextCollector{NbuCollector: collector, ctx: ctx})
+		promhttp.HandlerFor(prometheus.Gatherers{prometheus.DefaultGatherer, registry}, opts).ServeHTTP(w, r)
+	})
+}
+
+// scrapeTimeout returns the collection deadline derived from the scrape timeout header.
+// It reports false when the header is absent or invalid.
+func scrapeTimeout(value string) (time.Duration, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds <= 0 {
+		return 0, false
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > 2*scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return timeout, true
+}
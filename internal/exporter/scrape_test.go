@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestHandlerCancelsRequestsAtScrapeTimeout(t *testing.T) {
+	aborted := make(chan struct{}, 1)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			aborted <- struct{}{}
+		case <-time.After(10 * time.Second):
+			writeJSON(w, `{"data":[]}`)
+		}
+	})
+	cfg := testConfig(t, server)
+	cfg.Server.Collectors = []string{models.CollectorStorage}
+
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set(headerScrapeTimeout, "0.5")
+	response := httptest.NewRecorder()
+	start := time.Now()
+	NewNbuCollector(cfg).Handler(promhttp.HandlerOpts{}).ServeHTTP(response, request)
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("handler returned after %s, want about the 0.5s scrape timeout", elapsed)
+	}
+	if response.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", response.Code)
+	}
+	if body := response.Body.String(); !strings.Contains(body, "nbu_up 0") {
+		t.Errorf("metrics do not report nbu_up 0:\n%s", body)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("the NetBackup request was not aborted at the scrape timeout")
+	}
+}
@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fjacquet/nbu_exporter/internal/logging"
+)
+
+// SelfTest collects the metrics once within ctx and logs a summary of the collection:
+// whether NetBackup answered, the API version, the number of jobs and storage units
+// and the pages fetched per endpoint. It returns the collection error, if any.
+// The collection runs on a separate collector, so that it changes neither the state of
+// this one, such as the health status or the incremental job totals, nor its metrics.
+func (collector *NbuCollector) SelfTest(ctx context.Context) error {
+	metrics, err := NewNbuCollector(collector.cfg).gather(ctx)
+	logging.LogInfo(selfTestSummary(metrics, collector.cfg.NbuServer.APIVersion))
+	if err != nil {
+		return fmt.Errorf("startup self-test failed: %w", err)
+	}
+	return nil
+}
+
+// selfTestSummary describes the collected metrics in a single log line.
+func selfTestSummary(metrics *nbuMetrics, apiVersion string) string {
+	var jobs, storageUnits float64
+	for _, count := range metrics.jobsCount {
+		jobs += count
+	}
+	for _, count := range metrics.storageUnits {
+		storageUnits += count
+	}
+
+	pages := make([]string, 0, len(metrics.pagesFetched))
+	for endpoint, count := range metrics.pagesFetched {
+		pages = append(pages, fmt.Sprintf("%s=%g", endpoint, count))
+	}
+	sort.Strings(pages)
+	if len(pages) == 0 {
+		pages = append(pages, "none")
+	}
+
+	return fmt.Sprintf("Startup self-test: NetBackup up=%t, API version %s, %g jobs, %g storage units, pages fetched: %s",
+		metrics.up, valueOrDefault(apiVersion, "unset"), jobs, storageUnits, strings.Join(pages, " "))
+}
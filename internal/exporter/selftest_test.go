@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+func TestSelfTestSummaryAndState(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case isLookbackQuery(r):
+			writeJobPage(w, r, 1)
+		case r.URL.Path == defaultStoragePath:
+			writeJSON(w, `{"data":[{"id":"1","attributes":{"name":"disk","storageType":"DISK"}},{"id":"2","attributes":{"name":"tape","storageType":"Tape"}}]}`)
+		default:
+			writeJSON(w, `{"data":[]}`)
+		}
+	})
+	cfg := testConfig(t, server)
+	cfg.NbuServer.APIVersion = "12.0"
+	cfg.NbuServer.IncrementalJobs = true
+	collector := NewNbuCollector(cfg)
+
+	if err := collector.SelfTest(context.Background()); err != nil {
+		t.Fatalf("SelfTest() error = %v", err)
+	}
+	if !collector.lastAttempt.IsZero() || !collector.client.jobTotals.lastEnd.IsZero() {
+		t.Error("the self-test changed the state of the collector")
+	}
+
+	metrics := newNbuMetrics()
+	metrics.up = true
+	metrics.jobsCount["BACKUP|Standard|0"] = 2
+	metrics.storageUnits = map[string]float64{"DISK": 1, "Tape": 1}
+	metrics.pagesFetched = map[string]float64{models.CollectorStorage: 1, models.CollectorJobs: 3}
+	summary := selfTestSummary(metrics, cfg.NbuServer.APIVersion)
+	for _, want := range []string{"up=true", "API version 12.0", "2 jobs", "2 storage units", "pages fetched: jobs=3 storage=1"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary %q does not contain %q", summary, want)
+		}
+	}
+}
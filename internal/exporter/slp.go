@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+const slpStatusPath = "/storage/slps/status"
+
+// fetchSLPStatusPage retrieves one page of storage lifecycle policies and records their backlog.
+func (c *nbuClient) fetchSLPStatusPage(ctx context.Context, metrics *nbuMetrics, offset int) (int, error) {
+	var slps models.SLPStatus
+
+	url := buildURL(c.baseURL, slpStatusPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+	})
+
+	if err := c.fetchData(ctx, models.CollectorSLP, url, &slps); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorSLP)
+	checkPagination(c, models.CollectorSLP, len(slps.Data), slps.Meta.Pagination)
+
+	for _, data := range slps.Data {
+		metrics.slpBacklogBytes[data.Attributes.SlpName] = float64(data.Attributes.BacklogBytes)
+		metrics.slpIncompleteImages[data.Attributes.SlpName] = float64(data.Attributes.IncompleteImages)
+	}
+
+	if len(slps.Data) == 0 || slps.Meta.Pagination.Offset >= slps.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return slps.Meta.Pagination.Next, nil
+}
+
+// fetchSLPStatus retrieves the backlog of every storage lifecycle policy.
+func (c *nbuClient) fetchSLPStatus(ctx context.Context, metrics *nbuMetrics) error {
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchSLPStatusPage(ctx, metrics, offset)
+	})
+}
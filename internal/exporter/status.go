@@ -0,0 +1,65 @@
+package exporter
+
+import "strconv"
+
+// defaultStatusNames maps common NetBackup job status codes to human-readable names.
+var defaultStatusNames = map[string]string{
+	"0":    "success",
+	"1":    "partial",
+	"50":   "client_process_aborted",
+	"58":   "cannot_connect_to_client",
+	"71":   "no_files_in_file_list",
+	"96":   "no_media_available",
+	"150":  "terminated",
+	"196":  "backup_window_closed",
+	"2074": "disk_volume_down",
+}
+
+// jobFailed reports whether a job status code denotes a failure. Status 0 (success) and
+// 1 (partial success) are not failures; every other code is.
+func jobFailed(status int) bool {
+	return status != 0 && status != 1
+}
+
+// queueReasonNames maps NetBackup job queue reason codes to human-readable names.
+var queueReasonNames = map[int]string{
+	1: "media_in_use",
+	2: "drives_in_use",
+	3: "media_server_offline",
+	4: "robot_down",
+	5: "max_storage_unit_jobs",
+	6: "media_request_delay",
+	7: "local_drives_down",
+	8: "media_in_drive_in_use",
+}
+
+// queueReasonName returns the name of a job queue reason, or the code itself when unknown.
+func queueReasonName(code int) string {
+	if name, ok := queueReasonNames[code]; ok {
+		return name
+	}
+	return strconv.Itoa(code)
+}
+
+// statusNamer translates job status codes using the built-in names and configured overrides.
+type statusNamer map[string]string
+
+// newStatusNamer merges the configured overrides on top of the built-in status names.
+func newStatusNamer(overrides map[string]string) statusNamer {
+	names := make(statusNamer, len(defaultStatusNames)+len(overrides))
+	for code, name := range defaultStatusNames {
+		names[code] = name
+	}
+	for code, name := range overrides {
+		names[code] = name
+	}
+	return names
+}
+
+// name returns the human-readable name of a status code, or the code itself when unknown.
+func (n statusNamer) name(code string) string {
+	if name, ok := n[code]; ok {
+		return name
+	}
+	return code
+}
@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+const vmwareAssetsPath = "/asset-service/workloads/vmware/assets"
+
+// fetchVMwareProtectionPage retrieves one page of VMware virtual machines and counts the protected ones.
+// A virtual machine is protected when it belongs to at least one policy or protection plan.
+func (c *nbuClient) fetchVMwareProtectionPage(ctx context.Context, metrics *nbuMetrics, offset int) (int, error) {
+	var assets models.VMwareAssets
+
+	url := buildURL(c.baseURL, vmwareAssetsPath, map[string]string{
+		queryParamLimit:  pageLimit,
+		queryParamOffset: fmt.Sprintf("%d", offset),
+		queryParamFilter: "assetType eq 'vm'",
+	})
+
+	if err := c.fetchData(ctx, models.CollectorVMware, url, &assets); err != nil {
+		return -1, err
+	}
+	metrics.countPage(models.CollectorVMware)
+	checkPagination(c, models.CollectorVMware, len(assets.Data), assets.Meta.Pagination)
+
+	for _, data := range assets.Data {
+		if len(data.Attributes.CommonAssetAttributes.ActiveProtection.ProtectionDetailsList) > 0 {
+			metrics.vmsProtected++
+		} else {
+			metrics.vmsUnprotected++
+		}
+	}
+
+	if len(assets.Data) == 0 || assets.Meta.Pagination.Offset >= assets.Meta.Pagination.Last {
+		return -1, nil
+	}
+	return assets.Meta.Pagination.Next, nil
+}
+
+// fetchVMwareProtection counts the protected and unprotected VMware virtual machines.
+func (c *nbuClient) fetchVMwareProtection(ctx context.Context, metrics *nbuMetrics) error {
+	return c.handlePagination(func(offset int) (int, error) {
+		return c.fetchVMwareProtectionPage(ctx, metrics, offset)
+	})
+}
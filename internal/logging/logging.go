@@ -21,6 +21,12 @@ func LogInfo(msg string) {
 	log.WithFields(log.Fields{"job": programName}).Info(msg)
 }
 
+// LogDebug logs the provided message at debug level with the programName field.
+// This function should be used for details only useful when troubleshooting.
+func LogDebug(msg string) {
+	log.WithFields(log.Fields{"job": programName}).Debug(msg)
+}
+
 // LogPanic logs the provided error and exits the program with a non-zero exit code.
 // This function should be used to handle critical errors that prevent the program from continuing.
 func LogPanic(err error) {
@@ -40,20 +46,77 @@ func HandleError(err error) {
 	os.Exit(2)
 }
 
+// LogWarning logs the provided message as a warning with the programName field.
+// This function should be used to report suspicious but non-fatal conditions.
+func LogWarning(msg string) {
+	log.WithFields(log.Fields{"job": programName}).Warn(msg)
+}
+
 // LogError logs the provided error message with the programName field.
 // This function should be used to log recoverable errors that do not terminate the program.
 func LogError(msg string) {
 	log.WithFields(log.Fields{"job": programName}).Error(msg)
 }
 
-// PrepareLogs sets up logging.
-func PrepareLogs(logName string) error {
+// Log formats accepted by PrepareLogs.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// PrepareLogs sets up logging to stdout and the log file, in the given format.
+// An empty format selects JSON.
+func PrepareLogs(logName, format string) error {
+	formatter, err := newFormatter(format)
+	if err != nil {
+		return err
+	}
 	logFile, err := os.OpenFile(logName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %v", err)
 	}
 	mw := io.MultiWriter(os.Stdout, logFile)
 	log.SetOutput(mw)
-	log.SetFormatter(&log.JSONFormatter{PrettyPrint: true})
+	log.SetFormatter(formatter)
 	return nil
 }
+
+// ResolveLevel returns the log level to apply. The debug flag wins and selects debug;
+// otherwise the first non-empty level name is used, defaulting to info.
+func ResolveLevel(debug bool, names ...string) (log.Level, error) {
+	if debug {
+		return log.DebugLevel, nil
+	}
+	for _, name := range names {
+		if name != "" {
+			return ParseLevel(name)
+		}
+	}
+	return log.InfoLevel, nil
+}
+
+// ParseLevel converts a logrus level name, such as "warn" or "trace", into a level.
+func ParseLevel(name string) (log.Level, error) {
+	level, err := log.ParseLevel(name)
+	if err != nil {
+		return level, fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	return level, nil
+}
+
+// SetLevel sets the minimum level of logged messages.
+func SetLevel(level log.Level) {
+	log.SetLevel(level)
+}
+
+// newFormatter returns the logrus formatter for the given format name.
+func newFormatter(format string) (log.Formatter, error) {
+	switch format {
+	case "", FormatJSON:
+		return &log.JSONFormatter{PrettyPrint: true}, nil
+	case FormatText:
+		return &log.TextFormatter{FullTimestamp: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %s or %s)", format, FormatJSON, FormatText)
+	}
+}
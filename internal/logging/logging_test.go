@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestResolveLevel(t *testing.T) {
+	for _, tt := range []struct {
+		debug   bool
+		names   []string
+		want    log.Level
+		wantErr bool
+	}{
+		{want: log.InfoLevel},
+		{names: []string{"", ""}, want: log.InfoLevel},
+		{names: []string{"", "warn"}, want: log.WarnLevel},
+		{names: []string{"trace", "warn"}, want: log.TraceLevel},
+		{debug: true, names: []string{"error"}, want: log.DebugLevel},
+		{names: []string{"verbose"}, wantErr: true},
+	} {
+		got, err := ResolveLevel(tt.debug, tt.names...)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ResolveLevel(%t, %q) error = %v, wantErr %t", tt.debug, tt.names, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ResolveLevel(%t, %q) = %s, want %s", tt.debug, tt.names, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type AuditLogs struct {
+	Data []struct {
+		Links struct {
+			Self struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			AuditTime  time.Time `json:"auditTime"`
+			Category   string    `json:"category"`
+			Operation  string    `json:"operation"`
+			UserName   string    `json:"userName"`
+			DomainName string    `json:"domainName"`
+			Message    string    `json:"message"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Next   int `json:"next"`
+			Pages  int `json:"pages"`
+			Offset int `json:"offset"`
+			Last   int `json:"last"`
+			Limit  int `json:"limit"`
+			Count  int `json:"count"`
+			Page   int `json:"page"`
+			First  int `json:"first"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
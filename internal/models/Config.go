@@ -1,24 +1,750 @@
 package models
 
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SupportedAPIVersions lists the NetBackup API versions the exporter is known to work with.
+var SupportedAPIVersions = []string{"13.0", "12.0", "3.0"}
+
+// Names of the collectors that can be listed in server.collectors.
+const (
+	CollectorStorage      = "storage"
+	CollectorJobs         = "jobs"
+	CollectorMediaServers = "mediaservers"
+	CollectorImages       = "images"
+	CollectorSLP          = "slp"
+	CollectorDiskPools    = "diskpools"
+	CollectorVMware       = "vmware"
+	CollectorAudit        = "audit"
+	CollectorClients      = "clients"
+	CollectorCatalog      = "catalog"
+)
+
+// KnownCollectors lists every collector name accepted in server.collectors.
+var KnownCollectors = []string{CollectorStorage, CollectorJobs, CollectorMediaServers, CollectorImages, CollectorSLP, CollectorDiskPools, CollectorVMware, CollectorAudit, CollectorClients, CollectorCatalog}
+
+// DefaultCollectors are enabled when server.collectors is left empty.
+var DefaultCollectors = []string{CollectorStorage, CollectorJobs}
+
+// EnvironmentProduction is the server.environment value enabling stricter validation.
+const EnvironmentProduction = "production"
+
+// DefaultServerURI is the metrics path used when server.uri is not set.
+const DefaultServerURI = "/metrics"
+
+// DefaultShutdownTimeout is used when server.shutdownTimeout is not set.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultMaxClockSkew is used when nbuserver.maxClockSkew is not set.
+const DefaultMaxClockSkew = 30 * time.Second
+
+// DefaultCircuitBreakerCooldown is used when nbuserver.circuitBreaker.cooldown is not set.
+const DefaultCircuitBreakerCooldown = time.Minute
+
+// Values accepted for nbuserver.acceptFormat.
+const (
+	AcceptFormatVersioned = "versioned"
+	AcceptFormatPlain     = "plain"
+)
+
+// Values accepted for server.storageUnit, the unit of the storage capacity metrics.
+const (
+	StorageUnitBytes = "bytes"
+	StorageUnitMiB   = "mib"
+	StorageUnitGiB   = "gib"
+)
+
+// storageUnitSizes maps each storage unit to its size in bytes.
+var storageUnitSizes = map[string]float64{
+	StorageUnitBytes: 1,
+	StorageUnitMiB:   1 << 20,
+	StorageUnitGiB:   1 << 30,
+}
+
+// tlsVersions maps the values accepted for nbuserver.minTLSVersion to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// apiVersionPattern matches a well-formed API version such as "12.0".
+var apiVersionPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// labelNamePattern matches a valid Prometheus label name.
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// jobSortPattern matches a comma-separated list of attribute names, each optionally
+// prefixed with a minus sign for a descending order, such as "-startTime,jobId".
+var jobSortPattern = regexp.MustCompile(`^-?[A-Za-z]+(,-?[A-Za-z]+)*$`)
+
 // Config represents the configuration for the application.
 // It includes settings for the server and the NBU server.
 type Config struct {
 	Server struct {
-		Port              string `yaml:"port"`
-		Host              string `yaml:"host"`
-		URI               string `yaml:"uri"`
-		ScrappingInterval string `yaml:"scrappingInterval"`
-		LogName           string `yaml:"logName"`
+		Port              string            `yaml:"port"`
+		Host              string            `yaml:"host"`
+		URI               string            `yaml:"uri"`
+		ScrappingInterval string            `yaml:"scrappingInterval"`
+		LogName           string            `yaml:"logName"`
+		LogFormat         string            `yaml:"logFormat"`
+		LogLevel          string            `yaml:"logLevel"`
+		CacheEnabled      bool              `yaml:"cacheEnabled"`
+		StatusText        bool              `yaml:"statusText"`
+		StatusNames       map[string]string `yaml:"statusNames"`
+		ConstLabels       map[string]string `yaml:"constLabels"`
+		JobSubtypes       bool              `yaml:"jobSubtypes"`
+		StorageUnit       string            `yaml:"storageUnit"`
+		TLSCertFile       string            `yaml:"tlsCertFile"`
+		TLSKeyFile        string            `yaml:"tlsKeyFile"`
+		Collectors        []string          `yaml:"collectors"`
+		DisabledMetrics   []string          `yaml:"disabledMetrics"`
+		PushMode          bool              `yaml:"pushMode"`
+		PushEndpoint      string            `yaml:"pushEndpoint"`
+		ShutdownTimeout   string            `yaml:"shutdownTimeout"`
+		PIDFile           string            `yaml:"pidFile"`
+		UnixSocket        string            `yaml:"unixSocket"`
+		Environment       string            `yaml:"environment"`
+		StartupSelfTest   bool              `yaml:"startupSelfTest"`
+		BasicAuth         struct {
+			Username     string `yaml:"username"`
+			PasswordHash string `yaml:"passwordHash"`
+		} `yaml:"basicAuth"`
+		Secondary struct {
+			Host      string `yaml:"host"`
+			Port      string `yaml:"port"`
+			BasicAuth struct {
+				Username     string `yaml:"username"`
+				PasswordHash string `yaml:"passwordHash"`
+			} `yaml:"basicAuth"`
+		} `yaml:"secondary"`
 	} `yaml:"server"`
 
 	NbuServer struct {
-		Port        string `yaml:"port"`
-		Scheme      string `yaml:"scheme"`
-		URI         string `yaml:"uri"`
-		Domain      string `yaml:"domain"`
-		DomainType  string `yaml:"domainType"`
-		Host        string `yaml:"host"`
-		APIKey      string `yaml:"apiKey"`
-		ContentType string `yaml:"contentType"`
+		Port                    string            `yaml:"port"`
+		Scheme                  string            `yaml:"scheme"`
+		URI                     string            `yaml:"uri"`
+		Domain                  string            `yaml:"domain"`
+		DomainType              string            `yaml:"domainType"`
+		Host                    string            `yaml:"host"`
+		APIKey                  string            `yaml:"apiKey"`
+		APIVersion              string            `yaml:"apiVersion"`
+		AllowUnsupportedVersion bool              `yaml:"allowUnsupportedVersion"`
+		TokenEndpoint           string            `yaml:"tokenEndpoint"`
+		Username                string            `yaml:"username"`
+		Password                string            `yaml:"password"`
+		JobFilter               string            `yaml:"jobFilter"`
+		JobSort                 string            `yaml:"jobSort"`
+		JobsPath                string            `yaml:"jobsPath"`
+		StoragePath             string            `yaml:"storagePath"`
+		MaxJobSeries            int               `yaml:"maxJobSeries"`
+		PaginationConcurrency   int               `yaml:"paginationConcurrency"`
+		ProxyURL                string            `yaml:"proxyURL"`
+		PolicyAllowlist         []string          `yaml:"policyAllowlist"`
+		ExcludeStatuses         []int             `yaml:"excludeStatuses"`
+		MaxResponseBytes        int64             `yaml:"maxResponseBytes"`
+		ContentType             string            `yaml:"contentType"`
+		ExtraHeaders            map[string]string `yaml:"extraHeaders"`
+		AcceptFormat            string            `yaml:"acceptFormat"`
+		MinTLSVersion           string            `yaml:"minTLSVersion"`
+		RequestIDHeader         string            `yaml:"requestIDHeader"`
+		IncludeActiveJobs       bool              `yaml:"includeActiveJobs"`
+		EmitPolicyInfo          bool              `yaml:"emitPolicyInfo"`
+		UseFieldSelection       bool              `yaml:"useFieldSelection"`
+		IncrementalJobs         bool              `yaml:"incrementalJobs"`
+		InsecureSkipVerify      *bool             `yaml:"insecureSkipVerify"`
+		AllowInsecure           bool              `yaml:"allowInsecure"`
+		AcceptedContentTypes    []string          `yaml:"acceptedContentTypes"`
+		MaxClockSkew            string            `yaml:"maxClockSkew"`
+		Timeouts                struct {
+			Jobs    string `yaml:"jobs"`
+			Storage string `yaml:"storage"`
+		} `yaml:"timeouts"`
+		CircuitBreaker struct {
+			Failures int    `yaml:"failures"`
+			Cooldown string `yaml:"cooldown"`
+		} `yaml:"circuitBreaker"`
 	} `yaml:"nbuserver"`
 }
+
+// Validate checks the configuration and returns an error describing the first invalid setting.
+func (c *Config) Validate() error {
+	for _, validate := range []func() error{
+		c.validateServerURI,
+		c.validateAPIVersion,
+		c.validateTokenEndpoint,
+		c.validateTLS,
+		c.validateBasicAuth,
+		c.validateSecondary,
+		c.validateCollectors,
+		c.validateDisabledMetrics,
+		c.validateConstLabels,
+		c.validateJobFilter,
+		c.validateJobSort,
+		c.validateEndpointPaths,
+		c.validateMaxJobSeries,
+		c.validatePaginationConcurrency,
+		c.validateProxyURL,
+		c.validatePushEndpoint,
+		c.validateShutdownTimeout,
+		c.validateMaxResponseBytes,
+		c.validateTimeouts,
+		c.validateLogFormat,
+		c.validateLogLevel,
+		c.validateExtraHeaders,
+		c.validateAcceptFormat,
+		c.validateMinTLSVersion,
+		c.validateStorageUnit,
+		c.validateInsecureSkipVerify,
+		c.validateAcceptedContentTypes,
+		c.validateCircuitBreaker,
+		c.validateMaxClockSkew,
+	} {
+		if err := validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateJobFilter rejects a job filter made only of whitespace, and a job filter combined
+// with incrementalJobs, which relies on the default end time filter.
+func (c *Config) validateJobFilter() error {
+	if c.NbuServer.JobFilter != "" && strings.TrimSpace(c.NbuServer.JobFilter) == "" {
+		return fmt.Errorf("jobFilter must not be blank when provided")
+	}
+	if c.NbuServer.JobFilter != "" && c.NbuServer.IncrementalJobs {
+		return fmt.Errorf("jobFilter cannot be used with incrementalJobs")
+	}
+	return nil
+}
+
+// validateJobSort ensures the job sort order, when set, is a list of attribute names.
+func (c *Config) validateJobSort() error {
+	if c.NbuServer.JobSort != "" && !jobSortPattern.MatchString(c.NbuServer.JobSort) {
+		return fmt.Errorf("invalid jobSort %q (expected attribute names such as -startTime,jobId)", c.NbuServer.JobSort)
+	}
+	return nil
+}
+
+// validateEndpointPaths ensures custom endpoint paths are absolute.
+func (c *Config) validateEndpointPaths() error {
+	for name, path := range map[string]string{"jobsPath": c.NbuServer.JobsPath, "storagePath": c.NbuServer.StoragePath} {
+		if path != "" && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("%s must start with a slash, got %q", name, path)
+		}
+	}
+	return nil
+}
+
+// validateMaxJobSeries ensures the job series limit leaves room for at least one series besides "other".
+func (c *Config) validateMaxJobSeries() error {
+	if c.NbuServer.MaxJobSeries < 0 || c.NbuServer.MaxJobSeries == 1 {
+		return fmt.Errorf("maxJobSeries must be 0 (unlimited) or at least 2, got %d", c.NbuServer.MaxJobSeries)
+	}
+	return nil
+}
+
+// validatePaginationConcurrency ensures the number of concurrent page requests is not negative.
+func (c *Config) validatePaginationConcurrency() error {
+	if c.NbuServer.PaginationConcurrency < 0 {
+		return fmt.Errorf("paginationConcurrency must not be negative, got %d", c.NbuServer.PaginationConcurrency)
+	}
+	return nil
+}
+
+// validateProxyURL ensures the proxy URL, when set, is an absolute URL.
+func (c *Config) validateProxyURL() error {
+	if c.NbuServer.ProxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(c.NbuServer.ProxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxyURL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid proxyURL %q: expected a value such as http://proxy:3128", c.NbuServer.ProxyURL)
+	}
+	return nil
+}
+
+// validatePushEndpoint ensures an HTTP(S) endpoint is configured when push mode is enabled.
+func (c *Config) validatePushEndpoint() error {
+	if !c.Server.PushMode {
+		return nil
+	}
+	if c.Server.PushEndpoint == "" {
+		return fmt.Errorf("pushEndpoint is required when pushMode is enabled")
+	}
+	u, err := url.Parse(c.Server.PushEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid pushEndpoint: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid pushEndpoint %q: expected a value such as http://influxdb:8086/write?db=nbu", c.Server.PushEndpoint)
+	}
+	return nil
+}
+
+// validateExtraHeaders rejects extra headers that would replace the Accept or
+// Authorization headers set by the exporter.
+func (c *Config) validateExtraHeaders() error {
+	for name := range c.NbuServer.ExtraHeaders {
+		switch http.CanonicalHeaderKey(strings.TrimSpace(name)) {
+		case "", "Accept", "Authorization":
+			return fmt.Errorf("extraHeaders must not set %q", name)
+		}
+	}
+	return nil
+}
+
+// GetScrapingDuration returns the parsed server.scrappingInterval.
+func (c *Config) GetScrapingDuration() (time.Duration, error) {
+	interval, err := time.ParseDuration(c.Server.ScrappingInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid scrapping interval: %w", err)
+	}
+	return interval, nil
+}
+
+// GetShutdownTimeout returns how long a graceful shutdown may take.
+// It falls back to DefaultShutdownTimeout when the value is unset or invalid.
+func (c *Config) GetShutdownTimeout() time.Duration {
+	timeout, err := time.ParseDuration(c.Server.ShutdownTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return timeout
+}
+
+// validateShutdownTimeout ensures the shutdown timeout, when set, is a positive duration.
+func (c *Config) validateShutdownTimeout() error {
+	if c.Server.ShutdownTimeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(c.Server.ShutdownTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid shutdownTimeout: %w", err)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("shutdownTimeout must be positive, got %s", c.Server.ShutdownTimeout)
+	}
+	return nil
+}
+
+// validateServerURI normalizes the metrics path so that it starts with a slash,
+// defaulting to DefaultServerURI, and rejects values that cannot be a URL path.
+func (c *Config) validateServerURI() error {
+	uri := strings.TrimSpace(c.Server.URI)
+	if uri == "" {
+		c.Server.URI = DefaultServerURI
+		return nil
+	}
+	if strings.ContainsAny(uri, " ?#") || strings.Contains(uri, "://") {
+		return fmt.Errorf("invalid server uri %q: expected a path such as /metrics", c.Server.URI)
+	}
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	c.Server.URI = uri
+	return nil
+}
+
+// validateMaxResponseBytes rejects a negative response size limit.
+func (c *Config) validateMaxResponseBytes() error {
+	if c.NbuServer.MaxResponseBytes < 0 {
+		return fmt.Errorf("maxResponseBytes must be 0 (unlimited) or positive, got %d", c.NbuServer.MaxResponseBytes)
+	}
+	return nil
+}
+
+// EndpointTimeout returns the request timeout configured for the endpoint under
+// nbuserver.timeouts, or the fallback when none is set.
+func (c *Config) EndpointTimeout(endpoint string, fallback time.Duration) time.Duration {
+	var value string
+	switch endpoint {
+	case CollectorJobs, CollectorCatalog:
+		value = c.NbuServer.Timeouts.Jobs
+	case CollectorStorage:
+		value = c.NbuServer.Timeouts.Storage
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+// GetCircuitBreakerCooldown returns how long the circuit breaker stays open.
+// It falls back to DefaultCircuitBreakerCooldown when the value is unset or invalid.
+func (c *Config) GetCircuitBreakerCooldown() time.Duration {
+	cooldown, err := time.ParseDuration(c.NbuServer.CircuitBreaker.Cooldown)
+	if err != nil || cooldown <= 0 {
+		return DefaultCircuitBreakerCooldown
+	}
+	return cooldown
+}
+
+// validateCircuitBreaker rejects a negative failure threshold and an invalid cooldown.
+func (c *Config) validateCircuitBreaker() error {
+	if c.NbuServer.CircuitBreaker.Failures < 0 {
+		return fmt.Errorf("circuitBreaker.failures must be 0 (disabled) or positive, got %d", c.NbuServer.CircuitBreaker.Failures)
+	}
+	if c.NbuServer.CircuitBreaker.Cooldown == "" {
+		return nil
+	}
+	cooldown, err := time.ParseDuration(c.NbuServer.CircuitBreaker.Cooldown)
+	if err != nil {
+		return fmt.Errorf("invalid circuitBreaker.cooldown: %w", err)
+	}
+	if cooldown <= 0 {
+		return fmt.Errorf("circuitBreaker.cooldown must be positive, got %s", c.NbuServer.CircuitBreaker.Cooldown)
+	}
+	return nil
+}
+
+// GetMaxClockSkew returns the clock difference with NetBackup above which a warning is logged.
+// It falls back to DefaultMaxClockSkew when the value is unset or invalid.
+func (c *Config) GetMaxClockSkew() time.Duration {
+	skew, err := time.ParseDuration(c.NbuServer.MaxClockSkew)
+	if err != nil || skew <= 0 {
+		return DefaultMaxClockSkew
+	}
+	return skew
+}
+
+// validateMaxClockSkew ensures the clock skew threshold, when set, is a positive duration.
+func (c *Config) validateMaxClockSkew() error {
+	if c.NbuServer.MaxClockSkew == "" {
+		return nil
+	}
+	skew, err := time.ParseDuration(c.NbuServer.MaxClockSkew)
+	if err != nil {
+		return fmt.Errorf("invalid maxClockSkew: %w", err)
+	}
+	if skew <= 0 {
+		return fmt.Errorf("maxClockSkew must be positive, got %s", c.NbuServer.MaxClockSkew)
+	}
+	return nil
+}
+
+// validateTimeouts ensures the per-endpoint timeouts, when set, are positive durations.
+func (c *Config) validateTimeouts() error {
+	for name, value := range map[string]string{"jobs": c.NbuServer.Timeouts.Jobs, "storage": c.NbuServer.Timeouts.Storage} {
+		if value == "" {
+			continue
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeouts.%s: %w", name, err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("timeouts.%s must be positive, got %s", name, value)
+		}
+	}
+	return nil
+}
+
+// validateLogFormat ensures the log format is one supported by the logging package.
+func (c *Config) validateLogFormat() error {
+	switch c.Server.LogFormat {
+	case "", logging.FormatJSON, logging.FormatText:
+		return nil
+	default:
+		return fmt.Errorf("invalid logFormat %q (expected %s or %s)", c.Server.LogFormat, logging.FormatJSON, logging.FormatText)
+	}
+}
+
+// validateAcceptFormat ensures the Accept header format is one the exporter can build.
+func (c *Config) validateAcceptFormat() error {
+	switch c.NbuServer.AcceptFormat {
+	case "", AcceptFormatVersioned, AcceptFormatPlain:
+		return nil
+	default:
+		return fmt.Errorf("invalid acceptFormat %q (expected %s or %s)", c.NbuServer.AcceptFormat, AcceptFormatVersioned, AcceptFormatPlain)
+	}
+}
+
+// validateMinTLSVersion ensures the minimum TLS version, when set, is a known version.
+func (c *Config) validateMinTLSVersion() error {
+	if c.NbuServer.MinTLSVersion == "" {
+		return nil
+	}
+	if _, ok := tlsVersions[c.NbuServer.MinTLSVersion]; !ok {
+		return fmt.Errorf("invalid minTLSVersion %q (expected 1.0, 1.1, 1.2 or 1.3)", c.NbuServer.MinTLSVersion)
+	}
+	return nil
+}
+
+// GetMinTLSVersion returns the minimum TLS version for connections to NetBackup,
+// or 0 to keep the Go default when it is not set.
+func (c *Config) GetMinTLSVersion() uint16 {
+	return tlsVersions[c.NbuServer.MinTLSVersion]
+}
+
+// validateStorageUnit ensures the storage unit, when set, is bytes, mib or gib.
+func (c *Config) validateStorageUnit() error {
+	if c.Server.StorageUnit == "" {
+		return nil
+	}
+	if _, ok := storageUnitSizes[c.Server.StorageUnit]; !ok {
+		return fmt.Errorf("invalid storageUnit %q (expected %s, %s or %s)", c.Server.StorageUnit, StorageUnitBytes, StorageUnitMiB, StorageUnitGiB)
+	}
+	return nil
+}
+
+// GetStorageUnit returns the unit of the storage capacity metrics, bytes unless configured otherwise.
+func (c *Config) GetStorageUnit() string {
+	if _, ok := storageUnitSizes[c.Server.StorageUnit]; !ok {
+		return StorageUnitBytes
+	}
+	return c.Server.StorageUnit
+}
+
+// StorageUnitSize returns the number of bytes in one unit of the storage capacity metrics.
+func (c *Config) StorageUnitSize() float64 {
+	return storageUnitSizes[c.GetStorageUnit()]
+}
+
+// portCollisionWarning warns when the exporter listens on the port of the NetBackup API
+// on the same machine, a common copy-paste mistake.
+func (c *Config) portCollisionWarning() string {
+	if c.Server.UnixSocket != "" || c.Server.Port == "" || c.Server.Port != c.NbuServer.Port {
+		return ""
+	}
+	if c.Server.Host == c.NbuServer.Host || (isLocalHost(c.Server.Host) && isLocalHost(c.NbuServer.Host)) {
+		return fmt.Sprintf("server.port %s is also the NetBackup API port on the same host; the exporter may fail to listen or scrape itself", c.Server.Port)
+	}
+	return ""
+}
+
+// isLocalHost reports whether the host designates the local machine: empty, a wildcard
+// address, localhost or a loopback address.
+func isLocalHost(host string) bool {
+	switch host {
+	case "", "0.0.0.0", "::", "localhost":
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// SkipTLSVerify reports whether the NetBackup certificate is left unverified.
+// For compatibility with earlier releases, it is skipped unless insecureSkipVerify is set to false.
+func (c *Config) SkipTLSVerify() bool {
+	return c.NbuServer.InsecureSkipVerify == nil || *c.NbuServer.InsecureSkipVerify
+}
+
+// validateInsecureSkipVerify refuses an unverified NetBackup certificate in the production
+// environment unless allowInsecure is set.
+func (c *Config) validateInsecureSkipVerify() error {
+	if c.SkipTLSVerify() && c.Server.Environment == EnvironmentProduction && !c.NbuServer.AllowInsecure {
+		return fmt.Errorf("insecureSkipVerify is enabled (the default) in the production environment: set insecureSkipVerify to false, or allowInsecure to true to accept the risk")
+	}
+	return nil
+}
+
+// insecureSkipVerifyWarning warns when the NetBackup certificate is not verified.
+func (c *Config) insecureSkipVerifyWarning() string {
+	if !c.SkipTLSVerify() {
+		return ""
+	}
+	return "TLS certificate verification of the NetBackup server is DISABLED (insecureSkipVerify); set nbuserver.insecureSkipVerify to false to enable it"
+}
+
+// validateAcceptedContentTypes rejects blank accepted Content-Types, which would match any response.
+func (c *Config) validateAcceptedContentTypes() error {
+	for _, contentType := range c.NbuServer.AcceptedContentTypes {
+		if strings.TrimSpace(contentType) == "" {
+			return fmt.Errorf("acceptedContentTypes must not contain blank values")
+		}
+	}
+	return nil
+}
+
+// validateLogLevel ensures the log level, when set, is a logrus level name.
+func (c *Config) validateLogLevel() error {
+	if c.Server.LogLevel == "" {
+		return nil
+	}
+	_, err := logging.ParseLevel(c.Server.LogLevel)
+	return err
+}
+
+// CollectorEnabled reports whether the named collector should run.
+// The default collectors are used when none are configured.
+func (c *Config) CollectorEnabled(name string) bool {
+	if len(c.Server.Collectors) == 0 {
+		return slices.Contains(DefaultCollectors, name)
+	}
+	return slices.Contains(c.Server.Collectors, name)
+}
+
+// validateCollectors rejects collector names the exporter does not know.
+func (c *Config) validateCollectors() error {
+	for _, name := range c.Server.Collectors {
+		if !slices.Contains(KnownCollectors, name) {
+			return fmt.Errorf("unknown collector %q (known: %v)", name, KnownCollectors)
+		}
+	}
+	return nil
+}
+
+// validateDisabledMetrics rejects metric names the exporter does not emit.
+func (c *Config) validateDisabledMetrics() error {
+	for _, name := range c.Server.DisabledMetrics {
+		if !slices.Contains(KnownMetrics, name) {
+			return fmt.Errorf("unknown metric %q in disabledMetrics", name)
+		}
+	}
+	return nil
+}
+
+// validateConstLabels ensures the constant labels have valid names, not reserved by
+// Prometheus, and non-empty values.
+func (c *Config) validateConstLabels() error {
+	for name, value := range c.Server.ConstLabels {
+		if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid constLabels name %q", name)
+		}
+		if value == "" {
+			return fmt.Errorf("constLabels %q must not be empty", name)
+		}
+	}
+	return nil
+}
+
+// MetricEnabled reports whether the metric is not listed in server.disabledMetrics.
+func (c *Config) MetricEnabled(name string) bool {
+	return !slices.Contains(c.Server.DisabledMetrics, name)
+}
+
+// BasicAuthEnabled reports whether the metrics endpoint requires HTTP Basic Authentication.
+func (c *Config) BasicAuthEnabled() bool {
+	return c.Server.BasicAuth.Username != ""
+}
+
+// validateBasicAuth ensures a username and a bcrypt password hash are configured together,
+// for the main listener and for the secondary one.
+func (c *Config) validateBasicAuth() error {
+	if err := validateCredentials("basicAuth", c.Server.BasicAuth.Username, c.Server.BasicAuth.PasswordHash); err != nil {
+		return err
+	}
+	auth := c.Server.Secondary.BasicAuth
+	return validateCredentials("secondary.basicAuth", auth.Username, auth.PasswordHash)
+}
+
+// validateCredentials ensures the username and the bcrypt password hash of the named
+// setting are configured together.
+func validateCredentials(name, username, passwordHash string) error {
+	if (username == "") != (passwordHash == "") {
+		return fmt.Errorf("%s username and passwordHash must be set together", name)
+	}
+	if passwordHash != "" {
+		if _, err := bcrypt.Cost([]byte(passwordHash)); err != nil {
+			return fmt.Errorf("%s passwordHash is not a valid bcrypt hash: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SecondaryEnabled reports whether the metrics are also served on server.secondary.port.
+func (c *Config) SecondaryEnabled() bool {
+	return c.Server.Secondary.Port != ""
+}
+
+// validateSecondary ensures the secondary listener has a port, distinct from the main one.
+func (c *Config) validateSecondary() error {
+	if !c.SecondaryEnabled() {
+		if c.Server.Secondary.Host != "" || c.Server.Secondary.BasicAuth.Username != "" {
+			return fmt.Errorf("secondary.port is required to configure the secondary listener")
+		}
+		return nil
+	}
+	if c.Server.UnixSocket == "" && c.Server.Secondary.Port == c.Server.Port && c.Server.Secondary.Host == c.Server.Host {
+		return fmt.Errorf("secondary.port %s is already used by server.port", c.Server.Secondary.Port)
+	}
+	return nil
+}
+
+// TLSEnabled reports whether the exporter endpoint is served over HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.Server.TLSCertFile != "" && c.Server.TLSKeyFile != ""
+}
+
+// validateTLS ensures the certificate and key files are configured together.
+func (c *Config) validateTLS() error {
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		return fmt.Errorf("tlsCertFile and tlsKeyFile must be set together")
+	}
+	return nil
+}
+
+// validateTokenEndpoint ensures credentials are provided when re-authentication is enabled.
+func (c *Config) validateTokenEndpoint() error {
+	if c.NbuServer.TokenEndpoint == "" {
+		return nil
+	}
+	if c.NbuServer.Username == "" || c.NbuServer.Password == "" {
+		return fmt.Errorf("tokenEndpoint requires both username and password")
+	}
+	return nil
+}
+
+// Warnings returns the messages describing the valid but risky settings. Validate runs before
+// the logs are set up, so the caller logs them once they are.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	for _, warn := range []func() string{
+		c.unsupportedVersionWarning,
+		c.portCollisionWarning,
+		c.insecureSkipVerifyWarning,
+	} {
+		if warning := warn(); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// validateAPIVersion ensures the configured API version is well-formed and supported.
+// An unlisted version is only accepted when AllowUnsupportedVersion is set.
+func (c *Config) validateAPIVersion() error {
+	version := c.NbuServer.APIVersion
+	if version == "" {
+		return nil
+	}
+	if !apiVersionPattern.MatchString(version) {
+		return fmt.Errorf("invalid API version %q: expected a value such as \"12.0\"", version)
+	}
+	if slices.Contains(SupportedAPIVersions, version) {
+		return nil
+	}
+	if !c.NbuServer.AllowUnsupportedVersion {
+		return fmt.Errorf("unsupported API version %s (supported: %v); set allowUnsupportedVersion to use it anyway", version, SupportedAPIVersions)
+	}
+	return nil
+}
+
+// unsupportedVersionWarning warns when an unlisted API version is accepted through
+// AllowUnsupportedVersion.
+func (c *Config) unsupportedVersionWarning() string {
+	version := c.NbuServer.APIVersion
+	if version == "" || slices.Contains(SupportedAPIVersions, version) {
+		return ""
+	}
+	return fmt.Sprintf("API version %s is not in the supported list %v, continuing because allowUnsupportedVersion is set", version, SupportedAPIVersions)
+}
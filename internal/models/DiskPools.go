@@ -0,0 +1,32 @@
+package models
+
+type DiskPools struct {
+	Data []struct {
+		Links struct {
+			Self struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			Name                string `json:"name"`
+			SType               string `json:"sType"`
+			UsableSizeBytes     int64  `json:"usableSizeBytes"`
+			AvailableSpaceBytes int64  `json:"availableSpaceBytes"`
+			UsedCapacityBytes   int64  `json:"usedCapacityBytes"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Next   int `json:"next"`
+			Pages  int `json:"pages"`
+			Offset int `json:"offset"`
+			Last   int `json:"last"`
+			Limit  int `json:"limit"`
+			Count  int `json:"count"`
+			Page   int `json:"page"`
+			First  int `json:"first"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Hosts is the response of the /config/hosts endpoint, which lists every host
+// registered with the primary server. Unlike most endpoints, it is not paginated.
+type Hosts struct {
+	Hosts []struct {
+		UUID            string `json:"uuid"`
+		HostName        string `json:"hostName"`
+		OSType          string `json:"osType"`
+		CPUArchitecture string `json:"cpuArchitecture"`
+		IsSecureEnabled bool   `json:"isSecureEnabled"`
+	} `json:"hosts"`
+}
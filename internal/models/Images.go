@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type Images struct {
+	Data []struct {
+		Links struct {
+			Self struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			BackupID       string    `json:"backupId"`
+			BackupTime     time.Time `json:"backupTime"`
+			ClientName     string    `json:"clientName"`
+			PolicyName     string    `json:"policyName"`
+			PolicyType     string    `json:"policyType"`
+			ScheduleName   string    `json:"scheduleName"`
+			ScheduleType   string    `json:"scheduleType"`
+			RetentionLevel int       `json:"retentionLevel"`
+			Kilobytes      int64     `json:"kilobytes"`
+			NumberOfFiles  int       `json:"numberOfFiles"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Next   int `json:"next"`
+			Pages  int `json:"pages"`
+			Offset int `json:"offset"`
+			Last   int `json:"last"`
+			Limit  int `json:"limit"`
+			Count  int `json:"count"`
+			Page   int `json:"page"`
+			First  int `json:"first"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
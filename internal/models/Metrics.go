@@ -0,0 +1,69 @@
+package models
+
+// Names of the metrics exposed by the exporter.
+const (
+	MetricResponseTimeMS                  = "nbu_response_time_ms"
+	MetricDiskBytes                       = "nbu_disk_bytes"
+	MetricStorageUnitsCount               = "nbu_storage_units_count"
+	MetricStorageWormEnabled              = "nbu_storage_worm_enabled"
+	MetricJobsBytes                       = "nbu_jobs_bytes"
+	MetricJobsCount                       = "nbu_jobs_count"
+	MetricStatusCount                     = "nbu_status_count"
+	MetricLastScrapeTimestampSeconds      = "nbu_last_scrape_timestamp_seconds"
+	MetricMediaServerUp                   = "nbu_media_server_up"
+	MetricJobsSuccessRatio                = "nbu_jobs_success_ratio"
+	MetricAPIPagesFetched                 = "nbu_api_pages_fetched"
+	MetricUp                              = "nbu_up"
+	MetricCircuitBreakerOpen              = "nbu_circuit_breaker_open"
+	MetricServerTimeSkewSeconds           = "nbu_server_time_skew_seconds"
+	MetricJobsSeriesTruncated             = "nbu_jobs_series_truncated"
+	MetricJobsQueued                      = "nbu_jobs_queued"
+	MetricCatalogImagesCount              = "nbu_catalog_images_count"
+	MetricCatalogImagesBytes              = "nbu_catalog_images_bytes"
+	MetricAPIVersionNumber                = "nbu_api_version_number"
+	MetricExporterBuildInfo               = "nbu_exporter_build_info"
+	MetricClientsBackedUp                 = "nbu_clients_backed_up"
+	MetricRegisteredClients               = "nbu_registered_clients"
+	MetricDiskPoolBytes                   = "nbu_disk_pool_bytes"
+	MetricVMwareVMsProtected              = "nbu_vmware_vms_protected"
+	MetricVMwareVMsUnprotected            = "nbu_vmware_vms_unprotected"
+	MetricAuditEventsCount                = "nbu_audit_events_count"
+	MetricSLPBacklogBytes                 = "nbu_slp_backlog_bytes"
+	MetricSLPIncompleteImages             = "nbu_slp_incomplete_images"
+	MetricPolicyLastSuccessSeconds        = "nbu_policy_last_success_seconds"
+	MetricJobsThroughputBytesPerSecond    = "nbu_jobs_throughput_bytes_per_second"
+	MetricJobsFailedBytes                 = "nbu_jobs_failed_bytes"
+	MetricJobsRetriesCount                = "nbu_jobs_retries_count"
+	MetricJobsScheduleCount               = "nbu_jobs_schedule_count"
+	MetricJobsSubtypeCount                = "nbu_jobs_subtype_count"
+	MetricJobsTransportCount              = "nbu_jobs_transport_count"
+	MetricPolicyInfo                      = "nbu_policy_info"
+	MetricJobsElapsedSeconds              = "nbu_jobs_elapsed_seconds"
+	MetricOldestActiveJobSeconds          = "nbu_oldest_active_job_seconds"
+	MetricCatalogBackupLastSuccessSeconds = "nbu_catalog_backup_last_success_seconds"
+	MetricScrapeIntervalSeconds           = "nbu_scrape_interval_seconds"
+	MetricAPIRequestDurationSeconds       = "nbu_api_request_duration_seconds"
+	MetricAPIRateLimitedTotal             = "nbu_api_rate_limited_total"
+	MetricPaginationAnomaliesTotal        = "nbu_pagination_anomalies_total"
+	MetricPaginationMetadataMissingTotal  = "nbu_pagination_metadata_missing_total"
+	MetricStorageDuplicateKeysTotal       = "nbu_storage_duplicate_keys_total"
+)
+
+// KnownMetrics lists every metric the collector can emit, and so every name accepted in
+// server.disabledMetrics.
+var KnownMetrics = []string{
+	MetricResponseTimeMS, MetricDiskBytes, MetricStorageUnitsCount, MetricStorageWormEnabled,
+	MetricJobsBytes, MetricJobsCount, MetricStatusCount, MetricLastScrapeTimestampSeconds,
+	MetricMediaServerUp, MetricJobsSuccessRatio, MetricAPIPagesFetched, MetricUp,
+	MetricCircuitBreakerOpen, MetricServerTimeSkewSeconds, MetricJobsSeriesTruncated,
+	MetricJobsQueued, MetricCatalogImagesCount, MetricCatalogImagesBytes, MetricAPIVersionNumber,
+	MetricExporterBuildInfo, MetricClientsBackedUp, MetricRegisteredClients, MetricDiskPoolBytes,
+	MetricVMwareVMsProtected, MetricVMwareVMsUnprotected, MetricAuditEventsCount,
+	MetricSLPBacklogBytes, MetricSLPIncompleteImages, MetricPolicyLastSuccessSeconds,
+	MetricJobsThroughputBytesPerSecond, MetricJobsFailedBytes, MetricJobsRetriesCount,
+	MetricJobsScheduleCount, MetricJobsSubtypeCount, MetricJobsTransportCount, MetricPolicyInfo,
+	MetricJobsElapsedSeconds, MetricOldestActiveJobSeconds, MetricCatalogBackupLastSuccessSeconds,
+	MetricScrapeIntervalSeconds, MetricAPIRequestDurationSeconds, MetricAPIRateLimitedTotal,
+	MetricPaginationAnomaliesTotal, MetricPaginationMetadataMissingTotal,
+	MetricStorageDuplicateKeysTotal,
+}
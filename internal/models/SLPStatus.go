@@ -0,0 +1,30 @@
+package models
+
+type SLPStatus struct {
+	Data []struct {
+		Links struct {
+			Self struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			SlpName          string `json:"slpName"`
+			BacklogBytes     int64  `json:"backlogBytes"`
+			IncompleteImages int    `json:"incompleteImages"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Next   int `json:"next"`
+			Pages  int `json:"pages"`
+			Offset int `json:"offset"`
+			Last   int `json:"last"`
+			Limit  int `json:"limit"`
+			Count  int `json:"count"`
+			Page   int `json:"page"`
+			First  int `json:"first"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
@@ -24,6 +24,8 @@ type Storages struct {
 			MaxFragmentSizeMegabytes   int    `json:"maxFragmentSizeMegabytes"`
 			MaxConcurrentJobs          int    `json:"maxConcurrentJobs"`
 			OnDemandOnly               bool   `json:"onDemandOnly"`
+			WormCapable                bool   `json:"wormCapable"`
+			UseWorm                    bool   `json:"useWorm"`
 		} `json:"attributes,omitempty"`
 		Relationships struct {
 			DiskPool struct {
@@ -0,0 +1,15 @@
+package models
+
+// LoginRequest is the body sent to the NetBackup login endpoint to obtain a token.
+type LoginRequest struct {
+	UserName   string `json:"userName"`
+	Password   string `json:"password"`
+	DomainName string `json:"domainName,omitempty"`
+	DomainType string `json:"domainType,omitempty"`
+}
+
+// Token is the response returned by the NetBackup login endpoint.
+type Token struct {
+	Token    string `json:"token"`
+	Validity int    `json:"validity"`
+}
@@ -0,0 +1,38 @@
+package models
+
+type VMwareAssets struct {
+	Data []struct {
+		Links struct {
+			Self struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+		Type       string `json:"type"`
+		ID         string `json:"id"`
+		Attributes struct {
+			AssetType             string `json:"assetType"`
+			CommonAssetAttributes struct {
+				DisplayName      string `json:"displayName"`
+				ActiveProtection struct {
+					ProtectionDetailsList []struct {
+						ProtectionPlanName string `json:"protectionPlanName"`
+						PolicyName         string `json:"policyName"`
+						PolicyType         string `json:"policyType"`
+					} `json:"protectionDetailsList"`
+				} `json:"activeProtection"`
+			} `json:"commonAssetAttributes"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			Next   int `json:"next"`
+			Pages  int `json:"pages"`
+			Offset int `json:"offset"`
+			Last   int `json:"last"`
+			Limit  int `json:"limit"`
+			Count  int `json:"count"`
+			Page   int `json:"page"`
+			First  int `json:"first"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
@@ -0,0 +1,460 @@
+package models
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verifyingConfig returns an empty configuration that verifies the NetBackup certificate,
+// so that Warnings only reports the settings under test.
+func verifyingConfig() Config {
+	var cfg Config
+	verify := false
+	cfg.NbuServer.InsecureSkipVerify = &verify
+	return cfg
+}
+
+func TestValidateUnsupportedAPIVersion(t *testing.T) {
+	cfg := verifyingConfig()
+	cfg.NbuServer.APIVersion = "11.0"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "unsupported API version 11.0") || !strings.Contains(err.Error(), "allowUnsupportedVersion") {
+		t.Fatalf("Validate() error = %v, want an unsupported API version error naming allowUnsupportedVersion", err)
+	}
+
+	cfg.NbuServer.AllowUnsupportedVersion = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with allowUnsupportedVersion error = %v", err)
+	}
+	if warnings := cfg.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "11.0") {
+		t.Errorf("Warnings() = %q, want the unsupported API version", warnings)
+	}
+}
+
+func TestValidateAPIVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		wantErr bool
+	}{
+		{version: ""},
+		{version: "12.0"},
+		{version: "13.0"},
+		{version: "12", wantErr: true},
+		{version: "v12.0", wantErr: true},
+	} {
+		cfg := verifyingConfig()
+		cfg.NbuServer.APIVersion = tt.version
+		cfg.NbuServer.AllowUnsupportedVersion = true
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with apiVersion %q error = %v, wantErr %t", tt.version, err, tt.wantErr)
+		}
+		if warnings := cfg.Warnings(); !tt.wantErr && len(warnings) != 0 {
+			t.Errorf("Warnings() with apiVersion %q = %q, want none", tt.version, warnings)
+		}
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	for _, tt := range []struct {
+		cert, key string
+		wantErr   bool
+	}{
+		{},
+		{cert: "cert.pem", key: "key.pem"},
+		{cert: "cert.pem", wantErr: true},
+		{key: "key.pem", wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.TLSCertFile = tt.cert
+		cfg.Server.TLSKeyFile = tt.key
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with tlsCertFile %q and tlsKeyFile %q error = %v, wantErr %t", tt.cert, tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateJobFilter(t *testing.T) {
+	for _, tt := range []struct {
+		filter      string
+		incremental bool
+		wantErr     bool
+	}{
+		{filter: ""},
+		{filter: "jobType eq 'BACKUP'"},
+		{filter: "  ", wantErr: true},
+		{filter: "", incremental: true},
+		{filter: "jobType eq 'BACKUP'", incremental: true, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.JobFilter = tt.filter
+		cfg.NbuServer.IncrementalJobs = tt.incremental
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with jobFilter %q and incrementalJobs %t error = %v, wantErr %t", tt.filter, tt.incremental, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateEndpointPaths(t *testing.T) {
+	for _, tt := range []struct {
+		jobsPath, storagePath string
+		wantErr               bool
+	}{
+		{},
+		{jobsPath: "/custom/jobs", storagePath: "/custom/storage"},
+		{jobsPath: "custom/jobs", wantErr: true},
+		{storagePath: "storage", wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.JobsPath = tt.jobsPath
+		cfg.NbuServer.StoragePath = tt.storagePath
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with jobsPath %q and storagePath %q error = %v, wantErr %t", tt.jobsPath, tt.storagePath, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateMaxJobSeries(t *testing.T) {
+	for _, tt := range []struct {
+		max     int
+		wantErr bool
+	}{
+		{max: 0},
+		{max: 2},
+		{max: 100},
+		{max: 1, wantErr: true},
+		{max: -1, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.MaxJobSeries = tt.max
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with maxJobSeries %d error = %v, wantErr %t", tt.max, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateProxyURL(t *testing.T) {
+	for _, tt := range []struct {
+		proxyURL string
+		wantErr  bool
+	}{
+		{proxyURL: ""},
+		{proxyURL: "http://proxy:3128"},
+		{proxyURL: "proxy:3128", wantErr: true},
+		{proxyURL: "/proxy", wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.ProxyURL = tt.proxyURL
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with proxyURL %q error = %v, wantErr %t", tt.proxyURL, err, tt.wantErr)
+		}
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		timeout string
+		want    time.Duration
+		wantErr bool
+	}{
+		{timeout: "", want: DefaultShutdownTimeout},
+		{timeout: "30s", want: 30 * time.Second},
+		{timeout: "soon", want: DefaultShutdownTimeout, wantErr: true},
+		{timeout: "0s", want: DefaultShutdownTimeout, wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.ShutdownTimeout = tt.timeout
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with shutdownTimeout %q error = %v, wantErr %t", tt.timeout, err, tt.wantErr)
+		}
+		if got := cfg.GetShutdownTimeout(); got != tt.want {
+			t.Errorf("GetShutdownTimeout() with %q = %v, want %v", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestValidateServerURI(t *testing.T) {
+	for _, tt := range []struct {
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{uri: "metrics", want: "/metrics"},
+		{uri: "/metrics", want: "/metrics"},
+		{uri: "", want: DefaultServerURI},
+		{uri: "/metrics?x=1", wantErr: true},
+		{uri: "http://host/metrics", wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.URI = tt.uri
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with uri %q error = %v, wantErr %t", tt.uri, err, tt.wantErr)
+		}
+		if !tt.wantErr && cfg.Server.URI != tt.want {
+			t.Errorf("uri %q normalized to %q, want %q", tt.uri, cfg.Server.URI, tt.want)
+		}
+	}
+}
+
+func TestValidateMaxResponseBytes(t *testing.T) {
+	for _, tt := range []struct {
+		max     int64
+		wantErr bool
+	}{
+		{max: 0},
+		{max: 1 << 20},
+		{max: -1, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.MaxResponseBytes = tt.max
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with maxResponseBytes %d error = %v, wantErr %t", tt.max, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEndpointTimeout(t *testing.T) {
+	var cfg Config
+	cfg.NbuServer.Timeouts.Jobs = "5m"
+	if got := cfg.EndpointTimeout(CollectorJobs, time.Minute); got != 5*time.Minute {
+		t.Errorf("EndpointTimeout(jobs) = %v, want 5m", got)
+	}
+	if got := cfg.EndpointTimeout(CollectorStorage, time.Minute); got != time.Minute {
+		t.Errorf("EndpointTimeout(storage) = %v, want the 1m fallback", got)
+	}
+
+	cfg.NbuServer.Timeouts.Storage = "-1s"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted a negative storage timeout")
+	}
+}
+
+func TestValidateLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		level   string
+		wantErr bool
+	}{
+		{level: ""},
+		{level: "debug"},
+		{level: "warn"},
+		{level: "loud", wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.LogLevel = tt.level
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with logLevel %q error = %v, wantErr %t", tt.level, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateExtraHeaders(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "X-Gateway-Key"},
+		{name: "authorization", wantErr: true},
+		{name: "Accept", wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.ExtraHeaders = map[string]string{tt.name: "value"}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with extra header %q error = %v, wantErr %t", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateAcceptFormat(t *testing.T) {
+	for _, tt := range []struct {
+		format  string
+		wantErr bool
+	}{
+		{format: ""},
+		{format: AcceptFormatVersioned},
+		{format: AcceptFormatPlain},
+		{format: "json", wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.AcceptFormat = tt.format
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with acceptFormat %q error = %v, wantErr %t", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetMinTLSVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		want    uint16
+		wantErr bool
+	}{
+		{version: "", want: 0},
+		{version: "1.2", want: tls.VersionTLS12},
+		{version: "1.3", want: tls.VersionTLS13},
+		{version: "1.4", wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.MinTLSVersion = tt.version
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with minTLSVersion %q error = %v, wantErr %t", tt.version, err, tt.wantErr)
+		}
+		if !tt.wantErr && cfg.GetMinTLSVersion() != tt.want {
+			t.Errorf("GetMinTLSVersion() with %q = %#x, want %#x", tt.version, cfg.GetMinTLSVersion(), tt.want)
+		}
+	}
+}
+
+func TestWarningsReportsPortCollision(t *testing.T) {
+	cfg := verifyingConfig()
+	cfg.Server.Host = "0.0.0.0"
+	cfg.Server.Port = "1556"
+	cfg.NbuServer.Host = "localhost"
+	cfg.NbuServer.Port = "1556"
+	if warnings := cfg.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "server.port 1556") {
+		t.Errorf("Warnings() = %q, want the port collision", warnings)
+	}
+
+	cfg.NbuServer.Host = "nbu.example.com"
+	if warnings := cfg.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() = %q, want none for a remote NetBackup server", warnings)
+	}
+}
+
+func TestWarningsReportsInsecureSkipVerify(t *testing.T) {
+	var cfg Config
+	if warnings := cfg.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "insecureSkipVerify") {
+		t.Errorf("Warnings() = %q, want the disabled certificate verification", warnings)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want none outside the production environment", err)
+	}
+
+	cfg.Server.Environment = EnvironmentProduction
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() accepted insecureSkipVerify in the production environment")
+	}
+	cfg.NbuServer.AllowInsecure = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with allowInsecure error = %v", err)
+	}
+}
+
+func TestValidateAcceptedContentTypes(t *testing.T) {
+	for _, tt := range []struct {
+		contentTypes []string
+		wantErr      bool
+	}{
+		{},
+		{contentTypes: []string{"application/hal+json"}},
+		{contentTypes: []string{"application/json", " "}, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.AcceptedContentTypes = tt.contentTypes
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with acceptedContentTypes %q error = %v, wantErr %t", tt.contentTypes, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateDisabledMetrics(t *testing.T) {
+	for _, tt := range []struct {
+		names   []string
+		wantErr bool
+	}{
+		{},
+		{names: []string{"nbu_disk_bytes", "nbu_api_rate_limited_total"}},
+		{names: []string{"nbu_disk_byte"}, wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.DisabledMetrics = tt.names
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with disabledMetrics %q error = %v, wantErr %t", tt.names, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateConstLabels(t *testing.T) {
+	for _, tt := range []struct {
+		labels  map[string]string
+		wantErr bool
+	}{
+		{},
+		{labels: map[string]string{"env": "prod", "_site": "dc1"}},
+		{labels: map[string]string{"1env": "prod"}, wantErr: true},
+		{labels: map[string]string{"deployment-env": "prod"}, wantErr: true},
+		{labels: map[string]string{"__env": "prod"}, wantErr: true},
+		{labels: map[string]string{"env": ""}, wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.ConstLabels = tt.labels
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with constLabels %v error = %v, wantErr %t", tt.labels, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMaxClockSkew(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: DefaultMaxClockSkew},
+		{value: "2m", want: 2 * time.Minute},
+		{value: "0s", want: DefaultMaxClockSkew, wantErr: true},
+		{value: "soon", want: DefaultMaxClockSkew, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.MaxClockSkew = tt.value
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with maxClockSkew %q error = %v, wantErr %t", tt.value, err, tt.wantErr)
+		}
+		if got := cfg.GetMaxClockSkew(); got != tt.want {
+			t.Errorf("GetMaxClockSkew() with %q = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePaginationConcurrency(t *testing.T) {
+	for _, tt := range []struct {
+		concurrency int
+		wantErr     bool
+	}{
+		{concurrency: 0},
+		{concurrency: 4},
+		{concurrency: -1, wantErr: true},
+	} {
+		var cfg Config
+		cfg.NbuServer.PaginationConcurrency = tt.concurrency
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with paginationConcurrency %d error = %v, wantErr %t", tt.concurrency, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSecondary(t *testing.T) {
+	for _, tt := range []struct {
+		host, port, unixSocket, username string
+		wantErr                          bool
+	}{
+		{},
+		{port: "2113"},
+		{host: "10.0.0.1", port: "2112"},
+		{port: "2112", unixSocket: "/run/nbu_exporter.sock"},
+		{port: "2112", wantErr: true},
+		{host: "10.0.0.1", wantErr: true},
+		{port: "2113", username: "prometheus", wantErr: true},
+	} {
+		var cfg Config
+		cfg.Server.Port = "2112"
+		cfg.Server.UnixSocket = tt.unixSocket
+		cfg.Server.Secondary.Host = tt.host
+		cfg.Server.Secondary.Port = tt.port
+		cfg.Server.Secondary.BasicAuth.Username = tt.username
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with secondary %+v error = %v, wantErr %t", tt, err, tt.wantErr)
+		}
+	}
+}
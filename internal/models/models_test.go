@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// FuzzUnmarshalJobsAndStorages checks that no response body makes the job and storage
+// decoding panic, and that whatever decodes can be encoded and decoded again.
+func FuzzUnmarshalJobsAndStorages(f *testing.F) {
+	for _, seed := range []string{
+		`{"data": null}`,
+		`{"data": []}`,
+		`{"data":[{"id":"1","attributes":{"jobId":1,"jobType":"BACKUP","status":0,"endTime":"2024-01-01T00:00:00.000Z"}}],"meta":{"pagination":{"offset":0,"last":3,"next":1}}}`,
+		`{"data":[{"id":"disk","attributes":{"name":"disk","storageType":"DISK","freeCapacityBytes":1024,"useWorm":true}}]}`,
+		`{"data":[{"attributes":{"endTime":"not a time","kilobytesTransferred":"1"}}]}`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var jobs Jobs
+		if err := json.Unmarshal(body, &jobs); err == nil {
+			roundTrip(t, &jobs, &Jobs{})
+		}
+		var storages Storages
+		if err := json.Unmarshal(body, &storages); err == nil {
+			roundTrip(t, &storages, &Storages{})
+		}
+	})
+}
+
+// roundTrip encodes value and decodes the result into decoded, failing the test on error.
+func roundTrip(t *testing.T, value, decoded any) {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal(%T) error = %v", value, err)
+	}
+	if err := json.Unmarshal(encoded, decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", encoded, err)
+	}
+}
+
+// sampleJobs is a jobs page as returned by the NetBackup API, trimmed to a few attributes.
+const sampleJobs = `{
+  "data": [
+    {
+      "links": {
+        "self": {"href": "/admin/jobs/5812"},
+        "file-lists": {"href": "/admin/jobs/5812/file-lists"},
+        "try-logs": {"href": "/admin/jobs/5812/try-logs"}
+      },
+      "type": "job",
+      "id": "5812",
+      "attributes": {
+        "jobId": 5812,
+        "parentJobId": 5810,
+        "jobType": "BACKUP",
+        "jobSubType": "IMMEDIATE",
+        "policyType": "VMWARE",
+        "policyName": "vm-daily",
+        "scheduleType": "FULL",
+        "clientName": "vm01.example.com",
+        "status": 0,
+        "state": "DONE",
+        "kilobytesTransferred": 10485760,
+        "transportType": "SAN",
+        "dedupRatio": 12.5,
+        "startTime": "2024-03-01T22:00:05.000Z",
+        "endTime": "2024-03-01T22:41:37.000Z",
+        "try": 1,
+        "jobQueueReason": 0,
+        "elapsedTime": "00:41:32"
+      }
+    }
+  ],
+  "meta": {"pagination": {"next": 1, "pages": 3, "offset": 0, "last": 2, "limit": 1, "count": 3, "page": 0, "first": 0}},
+  "links": {"next": {"href": "/admin/jobs?page[offset]=1"}, "self": {"href": "/admin/jobs?page[offset]=0"}}
+}`
+
+func TestJobsRoundTrip(t *testing.T) {
+	var jobs Jobs
+	if err := json.Unmarshal([]byte(sampleJobs), &jobs); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(jobs.Data) != 1 {
+		t.Fatalf("decoded %d jobs, want 1", len(jobs.Data))
+	}
+	job := jobs.Data[0]
+	if job.ID != "5812" || job.Links.TryLogs.Href != "/admin/jobs/5812/try-logs" {
+		t.Errorf("job id and links = %q, %+v", job.ID, job.Links)
+	}
+	attributes := job.Attributes
+	if attributes.JobID != 5812 || attributes.PolicyType != "VMWARE" || attributes.KilobytesTransferred != 10485760 ||
+		attributes.DedupRatio != 12.5 || attributes.ElapsedTime != "00:41:32" {
+		t.Errorf("attributes = %+v", attributes)
+	}
+	if want := time.Date(2024, 3, 1, 22, 41, 37, 0, time.UTC); !attributes.EndTime.Equal(want) {
+		t.Errorf("endTime = %v, want %v", attributes.EndTime, want)
+	}
+	if jobs.Meta.Pagination.Next != 1 || jobs.Meta.Pagination.Last != 2 {
+		t.Errorf("pagination = %+v", jobs.Meta.Pagination)
+	}
+
+	var decoded Jobs
+	roundTrip(t, &jobs, &decoded)
+	if !reflect.DeepEqual(decoded, jobs) {
+		t.Errorf("round trip = %+v, want %+v", decoded, jobs)
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuth wraps the handler with HTTP Basic Authentication.
+// The username is compared in constant time and the password is checked against a bcrypt hash.
+// Both checks always run, so the response time does not reveal whether the username is valid.
+// Unauthenticated requests receive a 401 with a WWW-Authenticate challenge.
+func BasicAuth(next http.Handler, username, passwordHash string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(username)) == 1
+		passwordMatch := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+		if !ok || !userMatch || !passwordMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="nbu_exporter", charset="UTF-8"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
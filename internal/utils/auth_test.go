@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := BasicAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), "prometheus", string(hash))
+
+	for _, tt := range []struct {
+		name     string
+		username string
+		password string
+		noAuth   bool
+		want     int
+	}{
+		{name: "valid credentials", username: "prometheus", password: "secret", want: http.StatusNoContent},
+		{name: "wrong password", username: "prometheus", password: "wrong", want: http.StatusUnauthorized},
+		{name: "wrong username", username: "admin", password: "secret", want: http.StatusUnauthorized},
+		{name: "no credentials", noAuth: true, want: http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if !tt.noAuth {
+				r.SetBasicAuth(tt.username, tt.password)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("missing WWW-Authenticate challenge")
+			}
+		})
+	}
+}
@@ -1,13 +1,21 @@
 package utils
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/fjacquet/nbu_exporter/internal/logging"
 	"github.com/fjacquet/nbu_exporter/internal/models"
 	"gopkg.in/yaml.v2"
 )
 
+// envVarPattern matches ${NAME} references to environment variables, and their $${NAME} escapes.
+var envVarPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
 // test if a file exists
 //
 // fileExists checks if the given file exists.
@@ -20,19 +28,106 @@ func FileExists(filename string) bool {
 // readFile do read a yaml file
 // ReadFile reads the configuration from the specified YAML file.
 //
-// It opens the file, creates a YAML decoder, and decodes the configuration into the provided Config struct.
-// If any errors occur during the process, they are passed to the ProcessError function.
+// It reads the file, decodes the configuration into the provided Config struct and
+// expands ${NAME} environment variable references in the decoded values.
+// If any errors occur during the process, they are passed to the HandleError function.
 func ReadFile(Cfg *models.Config, filepath string) {
-	f, err := os.Open(filepath)
+	decodeFile(Cfg, filepath)
+	if err := expandEnv(Cfg); err != nil {
+		logging.HandleError(fmt.Errorf("%s: %w", filepath, err))
+	}
+}
+
+// ReadDir reads every *.yaml file of the directory, in lexical order, into the provided Config.
+//
+// Each file is decoded over the result of the previous ones: scalars and lists set in a
+// later file replace the earlier values, while maps are merged key by key.
+// Environment variable references are expanded once, after all the files are merged.
+// If any errors occur during the process, they are passed to the HandleError function.
+func ReadDir(Cfg *models.Config, dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
 	if err != nil {
 		logging.HandleError(err)
 	}
-	defer f.Close()
+	if len(files) == 0 {
+		logging.HandleError(fmt.Errorf("no *.yaml file found in %s", dir))
+	}
+	for _, file := range files {
+		decodeFile(Cfg, file)
+	}
+	if err := expandEnv(Cfg); err != nil {
+		logging.HandleError(fmt.Errorf("%s: %w", dir, err))
+	}
+}
 
-	decoder := yaml.NewDecoder(f)
-	err = decoder.Decode(Cfg)
+// decodeFile decodes the YAML file over the provided Config.
+func decodeFile(Cfg *models.Config, path string) {
+	content, err := os.ReadFile(path)
 	if err != nil {
 		logging.HandleError(err)
-		return
 	}
+
+	if err := yaml.Unmarshal(content, Cfg); err != nil {
+		logging.HandleError(err)
+	}
+}
+
+// expandEnv replaces ${NAME} references with the value of the environment variable in every
+// string of the configuration, including list items and map values.
+// The expansion happens after YAML parsing, so a value can never change the document structure.
+// $${NAME} is left as a literal ${NAME}.
+// A variable set to an empty string expands to nothing, while an undefined one is an error.
+func expandEnv(Cfg *models.Config) error {
+	var missing []string
+	expandValue(reflect.ValueOf(Cfg).Elem(), &missing)
+	if len(missing) > 0 {
+		return fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandValue expands the environment variable references of the strings held by v,
+// recording the names of undefined variables in missing.
+func expandValue(v reflect.Value, missing *[]string) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(expandString(v.String(), missing))
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			expandValue(v.Field(i), missing)
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			expandValue(v.Index(i), missing)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			expandValue(value, missing)
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			expandValue(v.Elem(), missing)
+		}
+	}
+}
+
+// expandString replaces the ${NAME} references of a single value.
+func expandString(value string, missing *[]string) string {
+	return envVarPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		name := envVarPattern.FindStringSubmatch(ref)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			*missing = append(*missing, name)
+			return ref
+		}
+		return env
+	})
 }
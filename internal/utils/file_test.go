@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fjacquet/nbu_exporter/internal/models"
+)
+
+// readConfig writes content to a temporary configuration file and reads it back.
+func readConfig(t *testing.T, content string) models.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var cfg models.Config
+	ReadFile(&cfg, path)
+	return cfg
+}
+
+func TestReadFileExpandsEnvInValues(t *testing.T) {
+	for _, value := range []string{
+		"plain",
+		"with: colon",
+		"with # hash",
+		"with\nnewline",
+		`with "double" and 'single' quotes`,
+		"x\nserver:\n  port: 9999",
+		"",
+	} {
+		t.Setenv("NBU_TEST_KEY", value)
+		cfg := readConfig(t, `
+server:
+    port: 2112
+    collectors: ["${NBU_TEST_KEY}"]
+    statusNames: {"2": "${NBU_TEST_KEY}"}
+nbuserver:
+    apiKey: "${NBU_TEST_KEY}" # ${NBU_TEST_UNDEFINED} in a comment is ignored
+`)
+		if cfg.NbuServer.APIKey != value {
+			t.Errorf("apiKey = %q, want %q", cfg.NbuServer.APIKey, value)
+		}
+		if len(cfg.Server.Collectors) != 1 || cfg.Server.Collectors[0] != value {
+			t.Errorf("collectors = %q, want [%q]", cfg.Server.Collectors, value)
+		}
+		if cfg.Server.StatusNames["2"] != value {
+			t.Errorf("statusNames[2] = %q, want %q", cfg.Server.StatusNames["2"], value)
+		}
+		if cfg.Server.Port != "2112" {
+			t.Errorf("port = %q with value %q, want 2112", cfg.Server.Port, value)
+		}
+	}
+}
+
+func TestReadFileKeepsEscapedReferences(t *testing.T) {
+	cfg := readConfig(t, `
+nbuserver:
+    password: "pa$${NBU_TEST_UNDEFINED}ss"
+    apiKey: "$5"
+`)
+	if cfg.NbuServer.Password != "pa${NBU_TEST_UNDEFINED}ss" {
+		t.Errorf("password = %q, want the literal reference", cfg.NbuServer.Password)
+	}
+	if cfg.NbuServer.APIKey != "$5" {
+		t.Errorf("apiKey = %q, want %q", cfg.NbuServer.APIKey, "$5")
+	}
+}
+
+func TestExpandEnvRejectsUndefinedVariables(t *testing.T) {
+	var cfg models.Config
+	cfg.NbuServer.APIKey = "${NBU_TEST_UNDEFINED}"
+	err := expandEnv(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "NBU_TEST_UNDEFINED") {
+		t.Errorf("expandEnv() error = %v, want one naming NBU_TEST_UNDEFINED", err)
+	}
+}
+
+func TestReadDirMergesFragmentsInOrder(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"10-base.yaml": `
+server:
+    port: 2112
+    collectors: ["jobs", "storage"]
+    statusNames: {"2": "partial"}
+nbuserver:
+    apiKey: "$${NBU_TEST_KEY}"
+`,
+		"20-site.yaml": `
+server:
+    collectors: ["jobs"]
+    statusNames: {"96": "no media"}
+nbuserver:
+    host: "${NBU_TEST_HOST}"
+`,
+		"notes.txt": "server: {port: 1}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("NBU_TEST_HOST", "nbu.example.com")
+	t.Setenv("NBU_TEST_KEY", "must-not-be-expanded")
+
+	var cfg models.Config
+	ReadDir(&cfg, dir)
+
+	if cfg.Server.Port != "2112" || cfg.NbuServer.Host != "nbu.example.com" {
+		t.Errorf("port, host = %q, %q, want 2112 and nbu.example.com", cfg.Server.Port, cfg.NbuServer.Host)
+	}
+	if strings.Join(cfg.Server.Collectors, ",") != "jobs" {
+		t.Errorf("collectors = %v, want the later list [jobs]", cfg.Server.Collectors)
+	}
+	if cfg.Server.StatusNames["2"] != "partial" || cfg.Server.StatusNames["96"] != "no media" {
+		t.Errorf("statusNames = %v, want both fragments merged", cfg.Server.StatusNames)
+	}
+	if cfg.NbuServer.APIKey != "${NBU_TEST_KEY}" {
+		t.Errorf("apiKey = %q, want the escaped reference expanded only once", cfg.NbuServer.APIKey)
+	}
+}
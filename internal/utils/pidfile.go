@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// WritePIDFile writes the current process ID to path, replacing any stale file left behind.
+func WritePIDFile(path string) error {
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return fmt.Errorf("cannot write pid file: %w", err)
+	}
+	return nil
+}
+
+// RemovePIDFile deletes the PID file written by WritePIDFile. A missing file is not an error.
+func RemovePIDFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove pid file: %w", err)
+	}
+	return nil
+}
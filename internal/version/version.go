@@ -0,0 +1,23 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Version and Commit identify the build. They are set at link time, e.g.
+// -ldflags "-X github.com/fjacquet/nbu_exporter/internal/version.Version=1.2.3".
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+// GoVersion returns the Go version the exporter was built with.
+func GoVersion() string {
+	return runtime.Version()
+}
+
+// String describes the build on one line, e.g. "1.2.3 (commit abc123, go1.23.4)".
+func String() string {
+	return fmt.Sprintf("%s (commit %s, %s)", Version, Commit, GoVersion())
+}
@@ -0,0 +1,15 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	Version, Commit = "1.2.3", "abc123"
+	t.Cleanup(func() { Version, Commit = "dev", "unknown" })
+
+	if got, want := String(), "1.2.3 (commit abc123, "+runtime.Version()+")"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
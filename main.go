@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/fjacquet/nbu_exporter/internal/exporter"
 	"github.com/fjacquet/nbu_exporter/internal/logging"
 	"github.com/fjacquet/nbu_exporter/internal/models"
 	"github.com/fjacquet/nbu_exporter/internal/utils"
+	"github.com/fjacquet/nbu_exporter/internal/version"
 	"github.com/go-resty/resty/v2"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -18,37 +22,157 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// refreshPath is the endpoint forcing a refresh of the cached metrics.
+const refreshPath = "/refresh"
+
+// healthPath is the endpoint reporting the status of the last collection.
+const healthPath = "/health"
+
+// selfTestTimeout bounds the startup self-test, like the default Prometheus scrape timeout
+// bounds a scrape, so that an unresponsive NetBackup server does not delay the startup.
+const selfTestTimeout = 10 * time.Second
+
 var (
 	ConfigFile  string
+	ConfigDir   string
 	Cfg         models.Config
 	Client      *resty.Client
 	programName string
 	Debug       bool
+	LogLevel    string
 	nbuRoot     string
 )
 
-// checkParams validates the command-line arguments and configuration file.
+// checkParams validates the command-line arguments and configuration file or directory.
 func checkParams() error {
+	if ConfigFile == "" && ConfigDir == "" {
+		return fmt.Errorf("one of --config or --config-dir is required")
+	}
+	if ConfigDir != "" {
+		if !utils.FileExists(ConfigDir) {
+			return fmt.Errorf("cannot find directory %s", ConfigDir)
+		}
+		return nil
+	}
 	if !utils.FileExists(ConfigFile) {
 		return fmt.Errorf("cannot find file %s", ConfigFile)
 	}
 	return nil
 }
 
-// startHTTPServer starts the HTTP server and handles graceful shutdown.
-func startHTTPServer() {
-	server := &http.Server{
-		Addr:    fmt.Sprintf("%s:%s", Cfg.Server.Host, Cfg.Server.Port),
-		Handler: http.DefaultServeMux,
+// loadConfig reads and validates the configuration file, exiting on error.
+func loadConfig() {
+	if err := checkParams(); err != nil {
+		log.Fatal(err)
 	}
 
+	if ConfigDir != "" {
+		utils.ReadDir(&Cfg, ConfigDir)
+	} else {
+		utils.ReadFile(&Cfg, ConfigFile)
+	}
+	if err := Cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serve serves the HTTP requests accepted by the listener in the background,
+// over HTTPS when a certificate is configured.
+func serve(server *http.Server, listener net.Listener) {
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if Cfg.TLSEnabled() {
+			err = server.ServeTLS(listener, Cfg.Server.TLSCertFile, Cfg.Server.TLSKeyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start HTTP server: %v", err)
 		}
 	}()
+}
+
+// listen opens the listener of the HTTP server: the Unix domain socket when
+// server.unixSocket is set, the TCP address otherwise.
+// A stale socket file left by a previous run is removed first.
+func listen(addr string) (net.Listener, error) {
+	if Cfg.Server.UnixSocket == "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot listen for HTTP requests: %w (check that server.host is a local address and no other process uses the port)", err)
+		}
+		return listener, nil
+	}
+	if err := os.Remove(Cfg.Server.UnixSocket); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return net.Listen("unix", Cfg.Server.UnixSocket)
+}
+
+// registerHandlers serves the metrics, refresh and health endpoints of the collector on mux.
+// The metrics and refresh endpoints are protected by server.basicAuth when set; /health is
+// always left open for liveness and readiness probes.
+func registerHandlers(mux *http.ServeMux, nbu *exporter.NbuCollector) {
+	auth := Cfg.Server.BasicAuth
+	mux.Handle(Cfg.Server.URI, withBasicAuth(metricsHandler(nbu), auth.Username, auth.PasswordHash))
+	mux.Handle(refreshPath, withBasicAuth(nbu.RefreshHandler(), auth.Username, auth.PasswordHash))
+	mux.Handle(healthPath, nbu.HealthHandler())
+}
+
+// registerSecondaryHandlers serves the metrics and health endpoints of the collector on the mux
+// of the secondary listener, with server.secondary.basicAuth in place of server.basicAuth.
+// The secondary listener is read-only: it does not serve the refresh endpoint.
+func registerSecondaryHandlers(mux *http.ServeMux, nbu *exporter.NbuCollector) {
+	auth := Cfg.Server.Secondary.BasicAuth
+	mux.Handle(Cfg.Server.URI, withBasicAuth(metricsHandler(nbu), auth.Username, auth.PasswordHash))
+	mux.Handle(healthPath, nbu.HealthHandler())
+}
+
+// metricsHandler serves the metrics of the collector, counting the scrapes in the default registry.
+func metricsHandler(nbu *exporter.NbuCollector) http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		nbu.Handler(promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
+
+// withBasicAuth protects the handler with HTTP Basic Authentication when a username is set.
+func withBasicAuth(handler http.Handler, username, passwordHash string) http.Handler {
+	if username == "" {
+		return handler
+	}
+	return utils.BasicAuth(handler, username, passwordHash)
+}
+
+// startHTTPServer serves HTTP requests on the listener, and with secondaryHandler on the
+// secondary listener when it is not nil, then handles their graceful shutdown.
+func startHTTPServer(listener, secondaryListener net.Listener, secondaryHandler http.Handler) {
+	server := &http.Server{
+		Addr:    listener.Addr().String(),
+		Handler: http.DefaultServeMux,
+	}
+	serve(server, listener)
+	servers := []*http.Server{server}
+
+	scheme := "http"
+	if Cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	if Cfg.Server.UnixSocket != "" {
+		log.Infof("Starting exporter on %s://unix:%s%s", scheme, Cfg.Server.UnixSocket, Cfg.Server.URI)
+	} else {
+		log.Infof("Starting exporter on %s://%s:%s%s", scheme, Cfg.Server.Host, Cfg.Server.Port, Cfg.Server.URI)
+	}
 
-	log.Infof("Starting exporter on %s:%s%s", Cfg.Server.Host, Cfg.Server.Port, Cfg.Server.URI)
+	if secondaryListener != nil {
+		secondary := &http.Server{
+			Addr:    secondaryListener.Addr().String(),
+			Handler: secondaryHandler,
+		}
+		serve(secondary, secondaryListener)
+		servers = append(servers, secondary)
+		log.Infof("Also serving metrics on %s://%s%s", scheme, secondary.Addr, Cfg.Server.URI)
+	}
 
 	// Graceful shutdown
 	stop := make(chan os.Signal, 1)
@@ -56,27 +180,40 @@ func startHTTPServer() {
 	<-stop
 
 	log.Info("Shutting down server...")
-	if err := server.Shutdown(nil); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	for _, server := range servers {
+		if err := shutdown(server); err != nil {
+			log.Fatalf("Server forced to shutdown: %v", err)
+		}
 	}
 	log.Info("Server exiting")
 }
 
+// shutdown gracefully stops the server, waiting at most server.shutdownTimeout for in-flight requests.
+func shutdown(server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), Cfg.GetShutdownTimeout())
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
 func main() {
 	var rootCmd = &cobra.Command{
 		Use:   "nbu_exporter",
 		Short: "NBU Exporter for Prometheus",
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := checkParams(); err != nil {
-				log.Fatal(err)
-			}
-
-			utils.ReadFile(&Cfg, ConfigFile)
+			loadConfig()
 			nbuRoot = fmt.Sprintf("%s://%s:%s%s", Cfg.NbuServer.Scheme, Cfg.NbuServer.Host, Cfg.NbuServer.Port, Cfg.NbuServer.URI)
 
-			if err := logging.PrepareLogs(Cfg.Server.LogName); err != nil {
+			if err := logging.PrepareLogs(Cfg.Server.LogName, Cfg.Server.LogFormat); err != nil {
+				log.Fatal(err)
+			}
+			level, err := logging.ResolveLevel(Debug, LogLevel, Cfg.Server.LogLevel)
+			if err != nil {
 				log.Fatal(err)
 			}
+			logging.SetLevel(level)
+			for _, warning := range Cfg.Warnings() {
+				log.Warn(warning)
+			}
 
 			log.Infof("Log name is: %s", Cfg.Server.LogName)
 			log.Infof("Starting %s...", programName)
@@ -86,19 +223,92 @@ func main() {
 				log.Infof("NBU server is on %s", nbuRoot)
 			}
 
-			// Register worker
+			if Cfg.Server.PIDFile != "" {
+				if err := utils.WritePIDFile(Cfg.Server.PIDFile); err != nil {
+					log.Fatal(err)
+				}
+				defer func() {
+					if err := utils.RemovePIDFile(Cfg.Server.PIDFile); err != nil {
+						log.Warn(err)
+					}
+				}()
+			}
+
+			// Bind before the first collection, so that an address in use fails right away.
+			listener, err := listen(fmt.Sprintf("%s:%s", Cfg.Server.Host, Cfg.Server.Port))
+			if err != nil {
+				log.Fatal(err)
+			}
+			var secondaryListener net.Listener
+			if Cfg.SecondaryEnabled() {
+				secondaryListener, err = net.Listen("tcp", fmt.Sprintf("%s:%s", Cfg.Server.Secondary.Host, Cfg.Server.Secondary.Port))
+				if err != nil {
+					log.Fatalf("Cannot listen for HTTP requests on server.secondary.port: %v", err)
+				}
+			}
+
+			// Create worker, registered for each scrape by its handler
 			nbu := exporter.NewNbuCollector(Cfg)
-			prometheus.MustRegister(nbu)
+			if err := prometheus.NewPedanticRegistry().Register(nbu); err != nil {
+				log.Fatalf("Invalid metrics: %v (check server.constLabels)", err)
+			}
+			if Cfg.Server.StartupSelfTest {
+				// NetBackup may recover later, so a failed self-test does not stop the exporter.
+				ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+				if err := nbu.SelfTest(ctx); err != nil {
+					log.Warn(err)
+				}
+				cancel()
+			}
+			if err := nbu.Start(); err != nil {
+				log.Fatal(err)
+			}
+			pusher := exporter.NewPusher(nbu)
+			if Cfg.Server.PushMode {
+				if err := pusher.Start(); err != nil {
+					log.Fatal(err)
+				}
+				log.Infof("Pushing metrics to %s every %s", Cfg.Server.PushEndpoint, Cfg.Server.ScrappingInterval)
+			}
 
 			// HTTP server startup
-			http.Handle(Cfg.Server.URI, promhttp.Handler())
-			startHTTPServer()
+			registerHandlers(http.DefaultServeMux, nbu)
+			secondaryMux := http.NewServeMux()
+			registerSecondaryHandlers(secondaryMux, nbu)
+			startHTTPServer(listener, secondaryListener, secondaryMux)
+			pusher.Stop()
+			nbu.Stop()
+		},
+	}
+
+	var probeCmd = &cobra.Command{
+		Use:   "probe",
+		Short: "Check which API versions and endpoints the NetBackup server answers",
+		Run: func(cmd *cobra.Command, args []string) {
+			loadConfig()
+			if !printProbeResults(os.Stdout, exporter.Probe(Cfg)) {
+				os.Exit(1)
+			}
+		},
+	}
+	rootCmd.AddCommand(probeCmd)
+
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the exporter version",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("nbu_exporter %s\n", version.String())
 		},
 	}
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.Version = version.String()
+	rootCmd.SetVersionTemplate("nbu_exporter {{.Version}}\n")
 
 	rootCmd.PersistentFlags().StringVarP(&ConfigFile, "config", "c", "", "Path to configuration file")
-	rootCmd.PersistentFlags().BoolVarP(&Debug, "debug", "d", false, "Enable debug mode")
-	rootCmd.MarkPersistentFlagRequired("config")
+	rootCmd.PersistentFlags().BoolVarP(&Debug, "debug", "d", false, "Enable debug mode (same as --log-level debug)")
+	rootCmd.PersistentFlags().StringVar(&LogLevel, "log-level", "", "Log level (trace, debug, info, warn, error), overrides server.logLevel")
+	rootCmd.PersistentFlags().StringVar(&ConfigDir, "config-dir", "", "Directory of *.yaml configuration fragments, merged in lexical order")
+	rootCmd.MarkFlagsMutuallyExclusive("config", "config-dir")
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fjacquet/nbu_exporter/internal/exporter"
+	"github.com/fjacquet/nbu_exporter/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// writeSelfSignedCert writes a certificate and key valid for 127.0.0.1 into a temporary directory.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "nbu_exporter test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServeOverTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	Cfg.Server.TLSCertFile = certFile
+	Cfg.Server.TLSKeyFile = keyFile
+	t.Cleanup(func() {
+		Cfg.Server.TLSCertFile = ""
+		Cfg.Server.TLSKeyFile = ""
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "nbu_disk_bytes 1\n")
+	})}
+	t.Cleanup(func() { server.Close() })
+	serve(server, listener)
+
+	pemCert, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pemCert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get("https://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatalf("HTTPS scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "nbu_disk_bytes") {
+		t.Errorf("HTTPS scrape = %d %q, want 200 with metrics", resp.StatusCode, body)
+	}
+}
+
+func TestPrintProbeResults(t *testing.T) {
+	failed := exporter.ProbeResult{Version: "12.0", Endpoint: "jobs", Status: http.StatusOK, ContentType: "text/html"}
+	succeeded := exporter.ProbeResult{Version: "13.0", Endpoint: "jobs", Status: http.StatusOK, ContentType: "application/json", JSON: true}
+
+	var out strings.Builder
+	if printProbeResults(&out, []exporter.ProbeResult{failed}) {
+		t.Error("printProbeResults() = true with only failed probes, want false")
+	}
+	if !strings.Contains(out.String(), "text/html") || !strings.Contains(out.String(), "FAIL") {
+		t.Errorf("output = %q, want the Content-Type and the failure", out.String())
+	}
+	if !printProbeResults(io.Discard, []exporter.ProbeResult{failed, succeeded}) {
+		t.Error("printProbeResults() = false with a successful probe, want true")
+	}
+}
+
+func TestShutdownRespectsTimeout(t *testing.T) {
+	Cfg.Server.ShutdownTimeout = "100ms"
+	t.Cleanup(func() { Cfg.Server.ShutdownTimeout = "" })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	received := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+	})}
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	serve(server, listener)
+	go http.Get("http://" + listener.Addr().String())
+	<-received
+
+	start := time.Now()
+	err = shutdown(server)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("shutdown() error = %v, want the deadline to be exceeded by the in-flight request", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > time.Second {
+		t.Errorf("shutdown() took %v, want about the configured 100ms", elapsed)
+	}
+}
+
+func TestServeOnUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "nbu_exporter.sock")
+	// A stale file left by a previous run must not prevent listening.
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	Cfg.Server.UnixSocket = socket
+	t.Cleanup(func() { Cfg.Server.UnixSocket = "" })
+
+	listener, err := listen("")
+	if err != nil {
+		t.Fatalf("listen() error = %v", err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "nbu_disk_bytes 1\n")
+	})}
+	serve(server, listener)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://unix/metrics")
+	if err != nil {
+		t.Fatalf("scrape over the Unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("scrape status = %d, want 200", resp.StatusCode)
+	}
+
+	server.Close()
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Errorf("socket file after shutdown: %v, want it removed", err)
+	}
+}
+
+func TestListenReportsBusyPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { busy.Close() })
+
+	listener, err := listen(busy.Addr().String())
+	if err == nil {
+		listener.Close()
+		t.Fatal("listen() on a busy port succeeded, want an error")
+	}
+	for _, want := range []string{"address already in use", "check that server.host is a local address and no other process uses the port"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("listen() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestCheckParamsRequiresConfig(t *testing.T) {
+	if err := checkParams(); err == nil || !strings.Contains(err.Error(), "--config") {
+		t.Errorf("checkParams() without --config or --config-dir error = %v, want it required", err)
+	}
+
+	ConfigDir = t.TempDir()
+	t.Cleanup(func() { ConfigDir = "" })
+	if err := checkParams(); err != nil {
+		t.Errorf("checkParams() with --config-dir error = %v", err)
+	}
+}
+
+func TestRegisterHandlersLeavesHealthUnauthenticated(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := Cfg
+	t.Cleanup(func() { Cfg = saved })
+	Cfg = models.Config{}
+	Cfg.Server.URI = "/metrics"
+	Cfg.Server.BasicAuth.Username = "prometheus"
+	Cfg.Server.BasicAuth.PasswordHash = string(hash)
+
+	mux := http.NewServeMux()
+	registerHandlers(mux, exporter.NewNbuCollector(Cfg))
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{path: "/metrics", want: http.StatusUnauthorized},
+		{path: refreshPath, want: http.StatusUnauthorized},
+		{path: healthPath, want: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s without credentials status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
+
+func TestRegisterSecondaryHandlersUsesSecondaryAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := Cfg
+	t.Cleanup(func() { Cfg = saved })
+	Cfg = models.Config{}
+	Cfg.Server.URI = "/metrics"
+	Cfg.Server.Secondary.Port = "2113"
+	Cfg.Server.Secondary.BasicAuth.Username = "prometheus"
+	Cfg.Server.Secondary.BasicAuth.PasswordHash = string(hash)
+
+	mux := http.NewServeMux()
+	registerSecondaryHandlers(mux, exporter.NewNbuCollector(Cfg))
+	for _, tt := range []struct {
+		path string
+		want int
+	}{
+		{path: "/metrics", want: http.StatusUnauthorized},
+		{path: refreshPath, want: http.StatusNotFound},
+		{path: healthPath, want: http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("GET %s on the secondary listener without credentials status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+	}
+}
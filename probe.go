@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/fjacquet/nbu_exporter/internal/exporter"
+)
+
+// printProbeResults writes the probe results as a table and reports whether any probe succeeded.
+func printProbeResults(w io.Writer, results []exporter.ProbeResult) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tENDPOINT\tSTATUS\tCONTENT-TYPE\tRESULT")
+
+	anyOK := false
+	for _, r := range results {
+		outcome := "FAIL"
+		if r.OK() {
+			outcome = "OK"
+			anyOK = true
+		}
+		switch {
+		case errors.Is(r.Err, exporter.ErrUnsupportedAPIVersion):
+			outcome = "UNSUPPORTED VERSION"
+		case r.Err != nil:
+			outcome = fmt.Sprintf("ERROR: %v", r.Err)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", r.Version, r.Endpoint, r.Status, r.ContentType, outcome)
+	}
+	tw.Flush()
+	return anyOK
+}